@@ -0,0 +1,108 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/jimdowning-cyclops/semver-calc-go/internal/changelog"
+	"github.com/jimdowning-cyclops/semver-calc-go/internal/config"
+)
+
+// changelogOptions controls how the changelog subcommand renders and writes changelogs.
+type changelogOptions struct {
+	Template *template.Template
+	Write    bool // Prepend to each product's configured changelog file instead of printing
+}
+
+// runChangelogCommand implements "semver-calc changelog", which renders the
+// relevant commits of each product-variant as Markdown.
+func runChangelogCommand(args []string) {
+	fs := flag.NewFlagSet("semver-calc changelog", flag.ExitOnError)
+	f := registerCommonFlags(fs)
+	templateFlag := fs.String("template", "", "Path to a custom Go text/template (overrides changelog.template in config)")
+	writeFlag := fs.Bool("write", false, "Prepend to each product's configured changelog file instead of printing")
+	fs.Parse(args)
+
+	cfg, opts := f.setup()
+
+	templatePath := *templateFlag
+	if templatePath == "" {
+		templatePath = cfg.Changelog.Template
+	}
+
+	var tmpl *template.Template
+	var err error
+	if templatePath != "" {
+		tmpl, err = changelog.LoadTemplate(templatePath)
+	} else {
+		tmpl, err = changelog.ParseTemplate("")
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := runChangelog(cfg, f.target, f.all, opts, changelogOptions{Template: tmpl, Write: *writeFlag}); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// runChangelog calculates versions exactly as runConfigMode does, then renders the
+// relevant commits of every product-variant with a bump.
+func runChangelog(cfg *config.Config, target string, all bool, opts calcOptions, chOpts changelogOptions) error {
+	results, err := calculateResults(cfg, target, all, opts)
+	if err != nil {
+		return err
+	}
+
+	sections := changelog.MergeSections(cfg.Changelog.Sections)
+	date := time.Now().Format("2006-01-02")
+
+	for _, r := range results {
+		if r.Error != "" {
+			debug("Skipping changelog for %s: %s", r.TagName, r.Error)
+			continue
+		}
+		if r.Bump == "none" {
+			debug("Skipping changelog for %s: no bump", r.TagName)
+			continue
+		}
+
+		release := changelog.Release{
+			Product:  r.Product,
+			Variant:  r.Variant,
+			TagName:  r.TagName,
+			Version:  r.Next,
+			Previous: r.Current,
+			Date:     date,
+
+			InheritedFrom: r.InheritedFrom,
+		}
+		var out strings.Builder
+		if err := changelog.Render(&out, chOpts.Template, changelog.Build(release, r.relevantCommits, sections)); err != nil {
+			return err
+		}
+
+		if !chOpts.Write {
+			fmt.Fprintln(os.Stdout, strings.TrimRight(out.String(), "\n")+"\n")
+			continue
+		}
+
+		path := cfg.ChangelogPath(config.ProductVariant{Product: r.Product, Variant: r.Variant})
+		if path == "" {
+			fmt.Fprintf(os.Stderr, "[WARN] No changelog path configured for %s, skipping\n", r.Product)
+			continue
+		}
+		if err := changelog.Prepend(path, out.String()); err != nil {
+			return fmt.Errorf("failed to write changelog for %s: %w", r.TagName, err)
+		}
+		fmt.Fprintf(os.Stderr, "[INFO] Wrote changelog for %s %s to %s\n", r.TagName, r.Next, path)
+	}
+
+	return failedResults(results)
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jimdowning-cyclops/semver-calc-go/internal/commit"
+	"github.com/jimdowning-cyclops/semver-calc-go/internal/config"
+	"github.com/jimdowning-cyclops/semver-calc-go/internal/git"
+	"github.com/jimdowning-cyclops/semver-calc-go/internal/matcher"
+)
+
+// ExplainResult is the JSON output of the explain subcommand for one product-variant.
+type ExplainResult struct {
+	Target  string          `json:"target"`
+	Product string          `json:"product"`
+	Variant string          `json:"variant,omitempty"`
+	LastTag string          `json:"lastTag,omitempty"`
+	Current string          `json:"current"`
+	Next    string          `json:"next"`
+	Bump    string          `json:"bump"`
+	Commits []ExplainCommit `json:"commits"`
+
+	InheritedFrom string `json:"inheritedFrom,omitempty"` // Dependency whose bump was inherited
+
+	// Why the calculation failed (only with --keep-going); current, next and bump are
+	// then unset, but the commits are still explained if they could be read
+	Error string `json:"error,omitempty"`
+
+	// Fetches run to complete the git history (single target output only)
+	Fetches []FetchResult `json:"fetches,omitempty"`
+}
+
+// ExplainCommit describes how a single commit since the last tag was evaluated.
+type ExplainCommit struct {
+	Hash          string              `json:"hash"`
+	Subject       string              `json:"subject"`
+	Type          string              `json:"type"`
+	Scope         string              `json:"scope,omitempty"`
+	Breaking      bool                `json:"breaking"`
+	FileMatches   []matcher.FileMatch `json:"fileMatches"`             // Matches across all products
+	ScopeHandling string              `json:"scopeHandling,omitempty"` // Empty if no file matched the target's product
+	Relevant      bool                `json:"relevant"`
+	Bump          string              `json:"bump"` // Contribution to the target's bump
+	Rule          string              `json:"rule,omitempty"`
+	Reason        string              `json:"reason"`
+}
+
+// runExplainCommand implements "semver-calc explain", which shows why each commit
+// since the last tag did or did not bump a target.
+func runExplainCommand(args []string) {
+	fs := flag.NewFlagSet("semver-calc explain", flag.ExitOnError)
+	f := registerCommonFlags(fs)
+	jsonFlag := fs.Bool("json", false, "Output JSON instead of a table")
+	fs.Parse(args)
+
+	cfg, opts := f.setup()
+
+	if err := runExplain(cfg, f.target, f.all, opts, *jsonFlag, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// runExplain evaluates each target exactly as calculateResults does, recording the
+// file, scope and bump decisions for every commit since the target's last tag.
+func runExplain(cfg *config.Config, target string, all bool, opts calcOptions, asJSON bool, w io.Writer) error {
+	if !git.IsGitRepository() {
+		return fmt.Errorf("not a git repository")
+	}
+
+	if err := resolveBranchRule(cfg, &opts); err != nil {
+		return err
+	}
+
+	m, err := matcher.NewMatcher(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create matcher: %w", err)
+	}
+
+	targets, err := resolveTargets(cfg, target, all)
+	if err != nil {
+		return err
+	}
+
+	histories, results, errs, err := evaluateTargets(cfg, m, targets, opts)
+	if err != nil {
+		return err
+	}
+
+	// With --keep-going, failed targets are explained with their error
+	failed := collectErrors(errs, opts.KeepGoing)
+	if failed != nil && !opts.KeepGoing {
+		return failed
+	}
+
+	var explanations []ExplainResult
+	for i, pv := range targets {
+		h, result := histories[i], results[i]
+
+		explanation := ExplainResult{
+			Target:        targetName(pv),
+			Product:       pv.Product,
+			Variant:       pv.Variant,
+			LastTag:       h.tagName,
+			Current:       result.Current,
+			Next:          result.Next,
+			Bump:          result.Bump,
+			InheritedFrom: result.InheritedFrom,
+			Commits:       []ExplainCommit{},
+		}
+		if errs[i] != nil {
+			explanation.Error = errs[i].Error()
+		}
+		rules := cfg.GetBumpRules(pv.Product)
+		cancelled := commit.CancelReverts(h.parsed)
+		for j, ci := range h.commits {
+			e := explainCommit(m, rules, pv, ci, h.parsed[j])
+			if cancelled[j] && e.Relevant {
+				e.Relevant, e.Bump, e.Rule = false, "none", ""
+				e.Reason = revertReason(h.parsed, j)
+			}
+			explanation.Commits = append(explanation.Commits, e)
+		}
+		explanations = append(explanations, explanation)
+	}
+
+	if asJSON {
+		encoder := json.NewEncoder(w)
+		var err error
+		if len(explanations) == 1 {
+			explanations[0].Fetches = fetchResults()
+			err = encoder.Encode(explanations[0])
+		} else {
+			err = encoder.Encode(struct {
+				Results []ExplainResult `json:"results"`
+				Fetches []FetchResult   `json:"fetches,omitempty"`
+			}{explanations, fetchResults()})
+		}
+		if err != nil {
+			return err
+		}
+		return failed
+	}
+
+	for i, e := range explanations {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		writeExplainTable(w, e)
+	}
+	return failed
+}
+
+// targetName returns the --target name of a product-variant.
+func targetName(pv config.ProductVariant) string {
+	if pv.Variant == "" {
+		return pv.Product
+	}
+	return pv.Product + "-" + pv.Variant
+}
+
+// explainCommit records how a commit was evaluated for a product-variant.
+func explainCommit(m *matcher.Matcher, rules commit.Rules, pv config.ProductVariant, ci git.CommitInfo, c commit.Commit) ExplainCommit {
+	e := ExplainCommit{
+		Hash:        c.Hash,
+		Subject:     ci.Subject,
+		Type:        c.Type,
+		Scope:       c.Scope,
+		Breaking:    c.Breaking,
+		FileMatches: m.ExplainFiles(ci.Files),
+		Relevant:    m.MatchesProductVariant(c, ci.Files, pv),
+		Bump:        "none",
+	}
+	if e.FileMatches == nil {
+		e.FileMatches = []matcher.FileMatch{}
+	}
+
+	if len(m.MatchingFiles(pv.Product, ci.Files)) == 0 {
+		if excluded := m.ExcludedFiles(pv.Product, ci.Files); len(excluded) > 0 {
+			e.Reason = fmt.Sprintf("changed files of %s are excluded by %q", pv.Product, excluded[0].Glob)
+			return e
+		}
+		if ignored := m.IgnoredFiles(ci.Files); len(ignored) > 0 && len(ignored) == len(ci.Files) {
+			e.Reason = fmt.Sprintf("changed files are ignored by %q", ignored[0].Glob)
+			return e
+		}
+		e.Reason = fmt.Sprintf("no changed files match %s", pv.Product)
+		return e
+	}
+
+	_, e.ScopeHandling = m.SelectVariants(pv.Product, c.ScopeList(), ci.Files)
+	if !e.Relevant {
+		switch e.ScopeHandling {
+		case matcher.ScopePath:
+			e.Reason = fmt.Sprintf("changed files belong to other variants of %s", pv.Product)
+		case matcher.ScopeDropped:
+			e.Reason = fmt.Sprintf("scope %q is unknown to %s and ignored", c.Scope, pv.Product)
+		case matcher.ScopeRejected:
+			e.Reason = fmt.Sprintf("scope %q is unknown to %s and rejected", c.Scope, pv.Product)
+		default:
+			e.Reason = fmt.Sprintf("scope %q selects another variant of %s", c.Scope, pv.Product)
+		}
+		return e
+	}
+
+	e.Bump, e.Rule = rules.BumpFor(c)
+	switch {
+	case e.Rule != "":
+		e.Reason = fmt.Sprintf("%s commit bumps %s", e.Rule, e.Bump)
+	case c.Type == "":
+		e.Reason = "not a conventional commit"
+	case c.Breaking:
+		e.Reason = fmt.Sprintf("breaking %s commit does not bump", c.Type)
+	default:
+		e.Reason = fmt.Sprintf("%s commit does not bump", c.Type)
+	}
+	return e
+}
+
+// revertReason explains why commits[i] was cancelled out by a revert.
+func revertReason(commits []commit.Commit, i int) string {
+	if c := commits[i]; c.Reverts != "" {
+		return fmt.Sprintf("reverts %s since the last tag, both cancel out", shortHash(c.Reverts))
+	}
+	for j := i - 1; j >= 0; j-- {
+		if commits[j].Reverts != "" && strings.HasPrefix(strings.ToLower(commits[i].Hash), commits[j].Reverts) {
+			return fmt.Sprintf("reverted by %s, both cancel out", shortHash(commits[j].Hash))
+		}
+	}
+	return "reverted since the last tag"
+}
+
+// writeExplainTable renders an explanation as a human-readable table.
+func writeExplainTable(w io.Writer, e ExplainResult) {
+	lastTag := e.LastTag
+	if lastTag == "" {
+		lastTag = "(none)"
+	}
+	fmt.Fprintf(w, "Target:   %s\n", e.Target)
+	if e.Error != "" {
+		fmt.Fprintf(w, "Last tag: %s\n", lastTag)
+		fmt.Fprintf(w, "Error:    %s\n\n", e.Error)
+	} else {
+		fmt.Fprintf(w, "Last tag: %s (%s)\n", lastTag, e.Current)
+		if e.InheritedFrom != "" {
+			fmt.Fprintf(w, "Next:     %s (%s, inherited from %s)\n\n", e.Next, e.Bump, e.InheritedFrom)
+		} else {
+			fmt.Fprintf(w, "Next:     %s (%s)\n\n", e.Next, e.Bump)
+		}
+	}
+
+	if len(e.Commits) == 0 {
+		fmt.Fprintln(w, "No commits since last tag.")
+		return
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "COMMIT\tTYPE\tSCOPE\tBREAKING\tFILES\tSCOPE HANDLING\tBUMP\tREASON")
+	for _, c := range e.Commits {
+		var files []string
+		for _, fm := range c.FileMatches {
+			product := fm.Product
+			if fm.Variant != "" {
+				product += "-" + fm.Variant
+			}
+			files = append(files, fmt.Sprintf("%s -> %s (%s)", fm.File, product, fm.Glob))
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			shortHash(c.Hash),
+			dash(c.Type),
+			dash(c.Scope),
+			yesNo(c.Breaking),
+			dash(strings.Join(files, ", ")),
+			dash(c.ScopeHandling),
+			c.Bump,
+			c.Reason,
+		)
+	}
+	tw.Flush()
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+	return hash
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
@@ -0,0 +1,213 @@
+package changelog
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/jimdowning-cyclops/semver-calc-go/internal/commit"
+	"github.com/jimdowning-cyclops/semver-calc-go/internal/config"
+)
+
+// BreakingTitle is the heading of the section listing breaking changes.
+const BreakingTitle = "Breaking Changes"
+
+// DependenciesTitle is the heading of the section naming a dependency whose bump was
+// inherited.
+const DependenciesTitle = "Dependencies"
+
+// DefaultSections are the type sections rendered when config does not override them.
+var DefaultSections = []config.ChangelogSection{
+	{Title: "Features", Types: []string{"feat"}},
+	{Title: "Bug Fixes", Types: []string{"fix"}},
+}
+
+// Entry is a single commit rendered in a changelog section.
+type Entry struct {
+	Hash        string
+	ShortHash   string
+	Type        string
+	Scope       string
+	Description string
+	Breaking    bool
+	References  []string // Issues referenced in the commit's footers, e.g. "#12"
+}
+
+// Section is a titled group of entries.
+type Section struct {
+	Title   string
+	Entries []Entry
+}
+
+// Release identifies the product-variant version a changelog is generated for.
+type Release struct {
+	Product  string
+	Variant  string
+	TagName  string
+	Version  string
+	Previous string
+	Date     string // YYYY-MM-DD
+
+	InheritedFrom string // Dependency (depends_on) whose bump was inherited, if any
+}
+
+// Data is the value passed to changelog templates.
+type Data struct {
+	Release
+	Sections []Section // Non-empty sections only, breaking changes first
+}
+
+// DefaultTemplate renders a Markdown release section.
+const DefaultTemplate = `## {{if .TagName}}{{.TagName}} {{end}}{{.Version}}{{with .Date}} ({{.}}){{end}}
+{{range .Sections}}
+### {{.Title}}
+
+{{range .Entries}}- {{with .Scope}}**{{.}}:** {{end}}{{.Description}}{{with .ShortHash}} ({{.}}){{end}}
+{{end}}{{end}}`
+
+// MergeSections returns the default sections with configured sections applied.
+// A configured section with the same title as a default replaces its types;
+// other configured sections are appended in order.
+func MergeSections(configured []config.ChangelogSection) []config.ChangelogSection {
+	result := make([]config.ChangelogSection, len(DefaultSections))
+	copy(result, DefaultSections)
+
+	for _, cs := range configured {
+		replaced := false
+		for i := range result {
+			if result[i].Title == cs.Title {
+				result[i].Types = cs.Types
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			result = append(result, cs)
+		}
+	}
+	return result
+}
+
+// Build groups commits into sections. Breaking commits are listed under
+// BreakingTitle using their BREAKING CHANGE text (falling back to the description),
+// and also under the section for their type. Commits whose type has no section
+// are left out. A release that inherited its bump from a dependency ends with a
+// DependenciesTitle section naming it, so a release without commits of its own is
+// not empty.
+func Build(release Release, commits []commit.Commit, sections []config.ChangelogSection) Data {
+	data := Data{Release: release}
+
+	var breaking []Entry
+	for _, c := range commits {
+		if !c.Breaking {
+			continue
+		}
+		e := newEntry(c)
+		if c.BreakingDescription != "" {
+			e.Description = c.BreakingDescription
+		}
+		breaking = append(breaking, e)
+	}
+	if len(breaking) > 0 {
+		data.Sections = append(data.Sections, Section{Title: BreakingTitle, Entries: breaking})
+	}
+
+	for _, s := range sections {
+		var entries []Entry
+		for _, c := range commits {
+			if contains(s.Types, c.Type) {
+				entries = append(entries, newEntry(c))
+			}
+		}
+		if len(entries) > 0 {
+			data.Sections = append(data.Sections, Section{Title: s.Title, Entries: entries})
+		}
+	}
+
+	if release.InheritedFrom != "" {
+		data.Sections = append(data.Sections, Section{Title: DependenciesTitle, Entries: []Entry{
+			{Description: "changes in " + release.InheritedFrom},
+		}})
+	}
+
+	return data
+}
+
+func newEntry(c commit.Commit) Entry {
+	short := c.Hash
+	if len(short) > 7 {
+		short = short[:7]
+	}
+	return Entry{
+		Hash:        c.Hash,
+		ShortHash:   short,
+		Type:        c.Type,
+		Scope:       c.Scope,
+		Description: c.Description,
+		Breaking:    c.Breaking,
+		References:  c.References(),
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseTemplate parses a changelog template. An empty text uses DefaultTemplate.
+func ParseTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		text = DefaultTemplate
+	}
+	return template.New("changelog").Option("missingkey=error").Parse(text)
+}
+
+// LoadTemplate reads and parses a changelog template file.
+func LoadTemplate(path string) (*template.Template, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read changelog template: %w", err)
+	}
+	return ParseTemplate(string(data))
+}
+
+// Render executes tmpl with data and writes the result to w.
+func Render(w io.Writer, tmpl *template.Template, data Data) error {
+	if err := tmpl.Execute(w, data); err != nil {
+		return fmt.Errorf("failed to render changelog: %w", err)
+	}
+	return nil
+}
+
+// Prepend inserts content at the top of the changelog file at path, creating it if needed.
+// A leading "# " title line (e.g., "# Changelog") is kept above the new content.
+func Prepend(path, content string) error {
+	existing, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read changelog: %w", err)
+	}
+
+	content = strings.TrimRight(content, "\n") + "\n"
+	var header, rest string
+	if strings.HasPrefix(string(existing), "# ") {
+		header, rest, _ = strings.Cut(string(existing), "\n")
+		header += "\n\n"
+		rest = strings.TrimLeft(rest, "\n")
+	} else {
+		rest = string(existing)
+	}
+	if rest != "" {
+		content += "\n"
+	}
+
+	if err := os.WriteFile(path, []byte(header+content+rest), 0644); err != nil {
+		return fmt.Errorf("failed to write changelog: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+package changelog
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jimdowning-cyclops/semver-calc-go/internal/commit"
+	"github.com/jimdowning-cyclops/semver-calc-go/internal/config"
+)
+
+func testCommits() []commit.Commit {
+	return []commit.Commit{
+		{Hash: "1111111aaaa", Type: "feat", Scope: "customerA", Description: "add login"},
+		{Hash: "2222222bbbb", Type: "fix", Description: "fix crash"},
+		{Hash: "3333333cccc", Type: "feat", Scope: "api", Description: "drop v1", Breaking: true, BreakingDescription: "v1 endpoints removed"},
+		{Hash: "4444444dddd", Type: "perf", Description: "faster startup"},
+		{Hash: "5555555eeee", Type: "chore", Description: "bump deps"},
+	}
+}
+
+func TestMergeSections(t *testing.T) {
+	got := MergeSections([]config.ChangelogSection{
+		{Title: "Bug Fixes", Types: []string{"fix", "revert"}},
+		{Title: "Performance", Types: []string{"perf"}},
+	})
+
+	want := []config.ChangelogSection{
+		{Title: "Features", Types: []string{"feat"}},
+		{Title: "Bug Fixes", Types: []string{"fix", "revert"}},
+		{Title: "Performance", Types: []string{"perf"}},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d sections, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].Title != want[i].Title || strings.Join(got[i].Types, ",") != strings.Join(want[i].Types, ",") {
+			t.Errorf("section %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	// Defaults must not be modified
+	if DefaultSections[1].Types[0] != "fix" || len(DefaultSections[1].Types) != 1 {
+		t.Errorf("DefaultSections was modified: %+v", DefaultSections)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	data := Build(Release{Version: "2.0.0"}, testCommits(), MergeSections(nil))
+
+	var titles []string
+	for _, s := range data.Sections {
+		titles = append(titles, s.Title)
+	}
+	if got := strings.Join(titles, ","); got != "Breaking Changes,Features,Bug Fixes" {
+		t.Fatalf("unexpected sections: %s", got)
+	}
+
+	breaking := data.Sections[0].Entries
+	if len(breaking) != 1 || breaking[0].Description != "v1 endpoints removed" {
+		t.Errorf("unexpected breaking entries: %+v", breaking)
+	}
+
+	features := data.Sections[1].Entries
+	if len(features) != 2 || features[1].Description != "drop v1" || features[0].ShortHash != "1111111" {
+		t.Errorf("unexpected feature entries: %+v", features)
+	}
+}
+
+func TestBuild_BreakingWithoutFooter(t *testing.T) {
+	commits := []commit.Commit{{Hash: "abc", Type: "fix", Description: "change default", Breaking: true}}
+	data := Build(Release{}, commits, MergeSections(nil))
+
+	if data.Sections[0].Title != BreakingTitle || data.Sections[0].Entries[0].Description != "change default" {
+		t.Errorf("expected breaking entry to fall back to description, got %+v", data.Sections[0])
+	}
+	if data.Sections[0].Entries[0].ShortHash != "abc" {
+		t.Errorf("expected short hash to keep short hashes intact, got %q", data.Sections[0].Entries[0].ShortHash)
+	}
+}
+
+func TestBuild_InheritedFrom(t *testing.T) {
+	data := Build(Release{Version: "1.0.1", InheritedFrom: "lib"}, nil, MergeSections(nil))
+
+	if len(data.Sections) != 1 || data.Sections[0].Title != DependenciesTitle {
+		t.Fatalf("expected only a dependencies section, got %+v", data.Sections)
+	}
+	if entries := data.Sections[0].Entries; len(entries) != 1 || entries[0].Description != "changes in lib" {
+		t.Errorf("unexpected dependency entries: %+v", entries)
+	}
+}
+
+func TestRender_DefaultTemplate(t *testing.T) {
+	tmpl, err := ParseTemplate("")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sections := MergeSections([]config.ChangelogSection{{Title: "Performance", Types: []string{"perf"}}})
+	release := Release{Product: "mobile", Variant: "customerA", TagName: "mobile-customerA", Version: "2.0.0", Date: "2026-01-02"}
+	var out strings.Builder
+	if err := Render(&out, tmpl, Build(release, testCommits(), sections)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := `## mobile-customerA 2.0.0 (2026-01-02)
+
+### Breaking Changes
+
+- **api:** v1 endpoints removed (3333333)
+
+### Features
+
+- **customerA:** add login (1111111)
+- **api:** drop v1 (3333333)
+
+### Bug Fixes
+
+- fix crash (2222222)
+
+### Performance
+
+- faster startup (4444444)
+`
+	if out.String() != want {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestRender_CustomTemplate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "changelog.tmpl")
+	content := `{{.Product}}@{{.Version}}:{{range .Sections}}{{range .Entries}} {{.Type}}/{{.ShortHash}}{{end}}{{end}}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl, err := LoadTemplate(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	commits := testCommits()[:2]
+	var out strings.Builder
+	if err := Render(&out, tmpl, Build(Release{Product: "web", Version: "1.1.0"}, commits, DefaultSections)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := out.String(); got != "web@1.1.0: feat/1111111 fix/2222222" {
+		t.Errorf("unexpected output %q", got)
+	}
+
+	if _, err := ParseTemplate("{{.Unclosed"); err == nil {
+		t.Error("expected error for invalid template")
+	}
+}
+
+func TestPrepend(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name     string
+		existing *string
+		want     string
+	}{
+		{
+			name:     "new file",
+			existing: nil,
+			want:     "## 1.1.0\n",
+		},
+		{
+			name:     "keeps title line",
+			existing: strPtr("# Changelog\n\n## 1.0.0\n\n- first\n"),
+			want:     "# Changelog\n\n## 1.1.0\n\n## 1.0.0\n\n- first\n",
+		},
+		{
+			name:     "no title line",
+			existing: strPtr("## 1.0.0\n"),
+			want:     "## 1.1.0\n\n## 1.0.0\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, strings.ReplaceAll(tt.name, " ", "-")+".md")
+			if tt.existing != nil {
+				if err := os.WriteFile(path, []byte(*tt.existing), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := Prepend(path, "## 1.1.0\n\n"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got:\n%q\nwant:\n%q", string(got), tt.want)
+			}
+		})
+	}
+}
+
+func strPtr(s string) *string {
+	return &s
+}
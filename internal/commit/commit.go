@@ -9,11 +9,34 @@ import (
 type Commit struct {
 	Hash        string
 	Type        string
-	Scope       string
+	Scope       string   // Scope as written, e.g. "customerA,customerB"
+	Scopes      []string // Individual scopes, split on the scope separators
 	Description string
 	Breaking    bool
+
+	// BreakingDescription is the text of the BREAKING CHANGE footer, if present.
+	BreakingDescription string
+
+	Body    string   // Message body without the footers
+	Footers []Footer // Footers and trailers, in message order
+
+	// Reverts is the (possibly abbreviated) hash of the commit this one reverts, from
+	// git's "This reverts commit <hash>." line.
+	Reverts string
 }
 
+// RevertType is the type of revert commits, including git's `Revert "<subject>"`.
+const RevertType = "revert"
+
+// gitRevertSubjectRegex matches the subject git gives revert commits.
+var gitRevertSubjectRegex = regexp.MustCompile(`^Revert "(.*)"$`)
+
+// hashRegex matches a full or abbreviated commit hash.
+var hashRegex = regexp.MustCompile(`^[0-9a-fA-F]{7,40}$`)
+
+// revertsRegex matches the line git adds to the body of revert commits.
+var revertsRegex = regexp.MustCompile(`(?m)^This reverts commit ([0-9a-fA-F]{7,40})\b`)
+
 // conventionalCommitRegex matches conventional commit format:
 // type(scope)!: description
 // type(scope): description
@@ -21,12 +44,40 @@ type Commit struct {
 // type: description
 var conventionalCommitRegex = regexp.MustCompile(`^(\w+)(?:\(([^)]+)\))?(!)?\s*:\s*(.*)$`)
 
-// Parse parses a conventional commit from subject and body.
+// DefaultScopeSeparators split multi-scope commits like "feat(customerA,customerB): ...".
+var DefaultScopeSeparators = []string{",", "/"}
+
+// Options controls how commits are parsed.
+type Options struct {
+	ScopeSeparators []string // Separators between multiple scopes; DefaultScopeSeparators if nil
+	ExpandSquash    bool     // Parse the commits listed in squash merge bodies (see ParseAll)
+}
+
+// squashItemRegex matches a commit listed in a squash merge body, e.g. "* feat: login".
+var squashItemRegex = regexp.MustCompile(`^[*-] +(.+)$`)
+
+// Parse parses a conventional commit from subject and body with default options.
 // Returns a Commit with Breaking=true if:
 // - Subject contains "!" before the colon (e.g., "feat(scope)!:")
-// - Body contains "BREAKING CHANGE:" or "BREAKING-CHANGE:"
+// - Body ends with a "BREAKING CHANGE:" or "BREAKING-CHANGE:" footer (see ParseFooters)
 func Parse(subject, body string) Commit {
+	return ParseWithOptions(subject, body, Options{})
+}
+
+// ParseWithOptions parses a conventional commit from subject and body like Parse.
+func ParseWithOptions(subject, body string, opts Options) Commit {
 	c := Commit{}
+	c.Body, c.Footers = ParseFooters(body)
+	if m := revertsRegex.FindStringSubmatch(body); m != nil {
+		c.Reverts = strings.ToLower(m[1])
+	}
+
+	// git's default revert message: the description is the reverted subject
+	if m := gitRevertSubjectRegex.FindStringSubmatch(subject); m != nil {
+		c.Type = RevertType
+		c.Description = m[1]
+		return c
+	}
 
 	matches := conventionalCommitRegex.FindStringSubmatch(subject)
 	if matches == nil {
@@ -37,47 +88,275 @@ func Parse(subject, body string) Commit {
 
 	c.Type = matches[1]
 	c.Scope = matches[2]
+	separators := opts.ScopeSeparators
+	if separators == nil {
+		separators = DefaultScopeSeparators
+	}
+	c.Scopes = SplitScope(c.Scope, separators)
 	c.Breaking = matches[3] == "!"
 	c.Description = matches[4]
 
-	// Check body for BREAKING CHANGE footer
-	if !c.Breaking && containsBreakingChange(body) {
-		c.Breaking = true
+	// Check footers for the first BREAKING CHANGE
+	for _, f := range c.Footers {
+		if IsBreakingToken(f.Token) {
+			c.Breaking = true
+			c.BreakingDescription = strings.Join(strings.Fields(f.Value), " ")
+			break
+		}
+	}
+
+	// A conventional revert may name the reverted commit in a Refs footer instead
+	if c.Type == RevertType && c.Reverts == "" {
+		for _, ref := range c.References() {
+			if hashRegex.MatchString(ref) {
+				c.Reverts = strings.ToLower(ref)
+				break
+			}
+		}
 	}
 
 	return c
 }
 
-// containsBreakingChange checks if the body contains a breaking change indicator.
-func containsBreakingChange(body string) bool {
-	bodyUpper := strings.ToUpper(body)
-	return strings.Contains(bodyUpper, "BREAKING CHANGE:") ||
-		strings.Contains(bodyUpper, "BREAKING-CHANGE:")
+// mergeSubjectRegex matches subjects generated for merges, such as "Merge pull request
+// #12 from org/branch", "Merge branch 'x' into 'main'" or "Merged in x (pull request #12)".
+var mergeSubjectRegex = regexp.MustCompile(`^Merged? (pull request|branch|remote-tracking branch|in) `)
+
+// MergeTitle returns the message of a merge commit with its pull request title as the
+// subject. Hosting services generate merge subjects and put the title on the first line
+// of the body; other messages are returned unchanged.
+func MergeTitle(subject, body string) (string, string) {
+	if !mergeSubjectRegex.MatchString(subject) || strings.TrimSpace(body) == "" {
+		return subject, body
+	}
+	title, rest, _ := strings.Cut(strings.TrimSpace(body), "\n")
+	return strings.TrimSpace(title), strings.TrimSpace(rest)
+}
+
+// ParseAll parses a commit like ParseWithOptions. With opts.ExpandSquash, a commit
+// whose subject is not a conventional commit, such as a squash merge titled
+// "Feature/login (#123)", is instead parsed into the conventional commits listed in
+// its body as "* feat: ..." or "- fix(scope): ..." lines. Each listed commit's body
+// runs until the next one. The commits are returned in body order.
+func ParseAll(subject, body string, opts Options) []Commit {
+	c := ParseWithOptions(subject, body, opts)
+	if !opts.ExpandSquash || c.Type != "" {
+		return []Commit{c}
+	}
+
+	var commits []Commit
+	var itemSubject string
+	var itemBody []string
+	flush := func() {
+		if itemSubject != "" {
+			commits = append(commits, ParseWithOptions(itemSubject, strings.Join(itemBody, "\n"), opts))
+		}
+	}
+	for _, line := range strings.Split(body, "\n") {
+		if m := squashItemRegex.FindStringSubmatch(line); m != nil {
+			if item := strings.TrimSpace(m[1]); ParseWithOptions(item, "", opts).Type != "" {
+				flush()
+				itemSubject, itemBody = item, nil
+				continue
+			}
+		}
+		if itemSubject != "" {
+			itemBody = append(itemBody, line)
+		}
+	}
+	flush()
+
+	if len(commits) == 0 {
+		return []Commit{c}
+	}
+	return commits
 }
 
-// DetermineBump analyzes a slice of commits and returns the highest bump level needed.
-// Returns "major", "minor", "patch", or "none".
-// Only considers commits with Type "feat" or "fix".
-func DetermineBump(commits []Commit) string {
-	bump := "none"
+// SplitScope splits a scope on any of the separators, trimming spaces and dropping
+// empty parts. Returns nil for an empty scope.
+func SplitScope(scope string, separators []string) []string {
+	parts := []string{scope}
+	for _, sep := range separators {
+		if sep == "" {
+			continue
+		}
+		var split []string
+		for _, part := range parts {
+			split = append(split, strings.Split(part, sep)...)
+		}
+		parts = split
+	}
 
-	for _, c := range commits {
-		// Breaking change always triggers major
-		if c.Breaking && (c.Type == "feat" || c.Type == "fix") {
-			return "major"
+	var scopes []string
+	for _, part := range parts {
+		if trimmed := strings.TrimSpace(part); trimmed != "" {
+			scopes = append(scopes, trimmed)
 		}
+	}
+	return scopes
+}
 
-		switch c.Type {
-		case "feat":
-			if bump != "major" {
-				bump = "minor"
+// ScopeList returns the commit's individual scopes, falling back to Scope for
+// commits built without Scopes.
+func (c Commit) ScopeList() []string {
+	if len(c.Scopes) > 0 || c.Scope == "" {
+		return c.Scopes
+	}
+	return []string{c.Scope}
+}
+
+// CancelReverts pairs each revert with the commit it reverts in the same list,
+// ordered newest first as in git log, and reports which commits cancel out: the
+// revert and the reverted commit, including every commit sharing its hash when a
+// squash merge was expanded. A revert of a revert re-applies the commits the first
+// revert undid. Reverts of commits outside the list stay unpaired and bump by the
+// revert bump rule.
+func CancelReverts(commits []Commit) []bool {
+	cancelled := make([]bool, len(commits))
+	targets := make([][]int, len(commits)) // Indexes of the commits a paired revert reverts
+	reverted := make([]bool, len(commits))
+
+	// toggle undoes or re-applies a commit, following chains of reverts
+	var toggle func(i int)
+	toggle = func(i int) {
+		if len(targets[i]) > 0 {
+			for _, t := range targets[i] {
+				toggle(t)
+			}
+			return
+		}
+		cancelled[i] = !cancelled[i]
+	}
+
+	for i := len(commits) - 1; i >= 0; i-- {
+		if commits[i].Reverts == "" {
+			continue
+		}
+		for j := len(commits) - 1; j > i; j-- {
+			if !reverted[j] && strings.HasPrefix(strings.ToLower(commits[j].Hash), commits[i].Reverts) {
+				reverted[j] = true
+				targets[i] = append(targets[i], j)
 			}
-		case "fix":
-			if bump == "none" {
-				bump = "patch"
+		}
+		if len(targets[i]) > 0 {
+			cancelled[i] = true
+			for _, t := range targets[i] {
+				toggle(t)
 			}
 		}
 	}
+	return cancelled
+}
+
+// BumpRule sets the bump levels of a commit type.
+type BumpRule struct {
+	Bump     string // Level for commits of the type: "major", "minor", "patch", "none", or RevertedBump
+	Breaking string // Level for breaking commits of the type; empty for "major"
+}
+
+// RevertedBump as the revert type's Bump level bumps unpaired reverts like the commit
+// they revert, parsed from their description.
+const RevertedBump = "reverted"
 
+// AnyType is the Rules key applying to commit types without a rule of their own.
+const AnyType = "*"
+
+// Rules maps commit types to their bump rules. Commits of types without a rule
+// (and no AnyType rule) do not bump.
+type Rules map[string]BumpRule
+
+// DefaultRules bump feat commits minor, fix commits patch, and breaking feat or fix
+// commits major.
+var DefaultRules = Rules{
+	"feat": {Bump: "minor"},
+	"fix":  {Bump: "patch"},
+}
+
+// DetermineBump analyzes a slice of commits and returns the highest bump level needed
+// under DefaultRules. Returns "major", "minor", "patch", or "none".
+func DetermineBump(commits []Commit) string {
+	return DefaultRules.DetermineBump(commits)
+}
+
+// BumpFor returns the bump level a single commit contributes under DefaultRules and
+// the rule that caused it (see Rules.BumpFor).
+func BumpFor(c Commit) (string, string) {
+	return DefaultRules.BumpFor(c)
+}
+
+// DetermineBump returns the highest bump level needed by the commits.
+// Returns "major", "minor", "patch", or "none".
+func (r Rules) DetermineBump(commits []Commit) string {
+	bump := "none"
+	for _, c := range commits {
+		level, _ := r.BumpFor(c)
+		bump = HigherBump(bump, level)
+	}
 	return bump
 }
+
+// BumpFor returns the bump level a single commit contributes and the rule that
+// caused it: "breaking" when the type's breaking level applies, or the commit type.
+// Returns "none" and an empty rule for commits that do not bump. A nil Rules uses
+// DefaultRules.
+func (r Rules) BumpFor(c Commit) (string, string) {
+	if r == nil {
+		r = DefaultRules
+	}
+	if c.Type == "" {
+		return "none", ""
+	}
+	rule, ok := r[c.Type]
+	if !ok {
+		rule, ok = r[AnyType]
+	}
+	if !ok {
+		return "none", ""
+	}
+
+	bump := rule.Bump
+	switch bump {
+	case "":
+		bump = "none"
+	case RevertedBump:
+		if c.Type != RevertType {
+			return "none", ""
+		}
+		bump, _ = r.BumpFor(Parse(c.Description, ""))
+	}
+	if c.Breaking {
+		breaking := rule.Breaking
+		if breaking == "" {
+			breaking = "major"
+		}
+		if bumpRank[breaking] > bumpRank[bump] {
+			return breaking, "breaking"
+		}
+	}
+	if bump == "none" {
+		return "none", ""
+	}
+	return bump, c.Type
+}
+
+// bumpRank orders bump levels from lowest to highest.
+var bumpRank = map[string]int{"none": 0, "patch": 1, "minor": 2, "major": 3}
+
+// CapBump limits a bump level to at most max. An empty max means no limit.
+func CapBump(bump, max string) string {
+	if max == "" {
+		return bump
+	}
+	if bumpRank[bump] > bumpRank[max] {
+		return max
+	}
+	return bump
+}
+
+// HigherBump returns the higher of two bump levels.
+func HigherBump(a, b string) string {
+	if bumpRank[b] > bumpRank[a] {
+		return b
+	}
+	return a
+}
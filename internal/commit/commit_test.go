@@ -1,6 +1,7 @@
 package commit
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -64,6 +65,8 @@ func TestParse(t *testing.T) {
 				Scope:       "app",
 				Description: "update API",
 				Breaking:    true,
+
+				BreakingDescription: "removed deprecated method",
 			},
 		},
 		{
@@ -75,17 +78,32 @@ func TestParse(t *testing.T) {
 				Scope:       "core",
 				Description: "refactor internals",
 				Breaking:    true,
+
+				BreakingDescription: "internal API changed",
 			},
 		},
 		{
-			name:    "breaking change case insensitive",
+			name:    "breaking change footer is case sensitive",
 			subject: "feat(sdk): update",
 			body:    "breaking change: something changed",
 			want: Commit{
 				Type:        "feat",
 				Scope:       "sdk",
 				Description: "update",
+				Breaking:    false,
+			},
+		},
+		{
+			name:    "breaking change footer text spans its paragraph only",
+			subject: "feat(api)!: drop v1",
+			body:    "Some context.\n\nBREAKING CHANGE: the v1 endpoints\nare gone\n\nRefs: #12",
+			want: Commit{
+				Type:        "feat",
+				Scope:       "api",
+				Description: "drop v1",
 				Breaking:    true,
+
+				BreakingDescription: "the v1 endpoints are gone",
 			},
 		},
 		{
@@ -160,10 +178,48 @@ func TestParse(t *testing.T) {
 			if got.Breaking != tt.want.Breaking {
 				t.Errorf("Parse() Breaking = %v, want %v", got.Breaking, tt.want.Breaking)
 			}
+			if got.BreakingDescription != tt.want.BreakingDescription {
+				t.Errorf("Parse() BreakingDescription = %q, want %q", got.BreakingDescription, tt.want.BreakingDescription)
+			}
+		})
+	}
+}
+
+func TestParse_Scopes(t *testing.T) {
+	tests := []struct {
+		name       string
+		subject    string
+		separators []string
+		want       []string
+	}{
+		{"single scope", "feat(customerA): x", nil, []string{"customerA"}},
+		{"comma separated", "feat(customerA,customerB): x", nil, []string{"customerA", "customerB"}},
+		{"slash separated with spaces", "fix(customerA / customerB)!: x", nil, []string{"customerA", "customerB"}},
+		{"empty parts dropped", "feat(customerA,,): x", nil, []string{"customerA"}},
+		{"no scope", "feat: x", nil, nil},
+		{"custom separator", "feat(a+b,c): x", []string{"+"}, []string{"a", "b,c"}},
+		{"splitting disabled", "feat(a,b): x", []string{}, []string{"a,b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseWithOptions(tt.subject, "", Options{ScopeSeparators: tt.separators})
+			if strings.Join(got.Scopes, "|") != strings.Join(tt.want, "|") || len(got.Scopes) != len(tt.want) {
+				t.Errorf("Scopes = %q, want %q", got.Scopes, tt.want)
+			}
 		})
 	}
 }
 
+func TestCommit_ScopeList(t *testing.T) {
+	if got := (Commit{Scope: "app"}).ScopeList(); len(got) != 1 || got[0] != "app" {
+		t.Errorf("ScopeList() = %q, want [app]", got)
+	}
+	if got := (Commit{}).ScopeList(); got != nil {
+		t.Errorf("ScopeList() = %q, want nil", got)
+	}
+}
+
 func TestDetermineBump(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -259,3 +315,306 @@ func TestDetermineBump(t *testing.T) {
 		})
 	}
 }
+
+func TestCapBump(t *testing.T) {
+	tests := []struct {
+		bump string
+		max  string
+		want string
+	}{
+		{"major", "", "major"},
+		{"major", "patch", "patch"},
+		{"minor", "patch", "patch"},
+		{"major", "minor", "minor"},
+		{"patch", "minor", "patch"},
+		{"none", "patch", "none"},
+		{"minor", "major", "minor"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.bump+"/"+tt.max, func(t *testing.T) {
+			if got := CapBump(tt.bump, tt.max); got != tt.want {
+				t.Errorf("CapBump(%q, %q) = %q, want %q", tt.bump, tt.max, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHigherBump(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want string
+	}{
+		{"none", "patch", "patch"},
+		{"minor", "patch", "minor"},
+		{"minor", "major", "major"},
+		{"none", "none", "none"},
+	}
+
+	for _, tt := range tests {
+		if got := HigherBump(tt.a, tt.b); got != tt.want {
+			t.Errorf("HigherBump(%q, %q) = %q, want %q", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestBumpFor(t *testing.T) {
+	tests := []struct {
+		name     string
+		commit   Commit
+		wantBump string
+		wantRule string
+	}{
+		{"feat", Commit{Type: "feat"}, "minor", "feat"},
+		{"fix", Commit{Type: "fix"}, "patch", "fix"},
+		{"breaking feat", Commit{Type: "feat", Breaking: true}, "major", "breaking"},
+		{"breaking fix", Commit{Type: "fix", Breaking: true}, "major", "breaking"},
+		{"breaking refactor", Commit{Type: "refactor", Breaking: true}, "none", ""},
+		{"chore", Commit{Type: "chore"}, "none", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bump, rule := BumpFor(tt.commit)
+			if bump != tt.wantBump || rule != tt.wantRule {
+				t.Errorf("BumpFor() = (%q, %q), want (%q, %q)", bump, rule, tt.wantBump, tt.wantRule)
+			}
+		})
+	}
+}
+
+func TestRules_BumpFor(t *testing.T) {
+	rules := Rules{
+		"feat":     {Bump: "minor"},
+		"fix":      {Bump: "patch"},
+		"perf":     {Bump: "patch"},
+		"refactor": {Bump: "none", Breaking: "major"},
+		"docs":     {Bump: "none", Breaking: "none"},
+		"deps":     {Bump: "patch", Breaking: "minor"},
+	}
+
+	tests := []struct {
+		name     string
+		rules    Rules
+		commit   Commit
+		wantBump string
+		wantRule string
+	}{
+		{"configured type", rules, Commit{Type: "perf"}, "patch", "perf"},
+		{"breaking defaults to major", rules, Commit{Type: "perf", Breaking: true}, "major", "breaking"},
+		{"non-bumping type with breaking level", rules, Commit{Type: "refactor", Breaking: true}, "major", "breaking"},
+		{"non-bumping type", rules, Commit{Type: "refactor"}, "none", ""},
+		{"breaking ignored", rules, Commit{Type: "docs", Breaking: true}, "none", ""},
+		{"custom breaking level", rules, Commit{Type: "deps", Breaking: true}, "minor", "breaking"},
+		{"type without rule", rules, Commit{Type: "chore", Breaking: true}, "none", ""},
+		{"any type rule", Rules{AnyType: {Bump: "none"}}, Commit{Type: "chore", Breaking: true}, "major", "breaking"},
+		{"non-conventional commit", Rules{AnyType: {Bump: "patch"}}, Commit{Description: "wip"}, "none", ""},
+		{"nil rules use defaults", nil, Commit{Type: "feat"}, "minor", "feat"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bump, rule := tt.rules.BumpFor(tt.commit)
+			if bump != tt.wantBump || rule != tt.wantRule {
+				t.Errorf("BumpFor() = (%q, %q), want (%q, %q)", bump, rule, tt.wantBump, tt.wantRule)
+			}
+		})
+	}
+
+	if got := rules.DetermineBump([]Commit{{Type: "perf"}, {Type: "deps", Breaking: true}, {Type: "docs"}}); got != "minor" {
+		t.Errorf("DetermineBump() = %q, want minor", got)
+	}
+}
+
+func TestParse_Revert(t *testing.T) {
+	git := Parse(`Revert "feat(app): add login"`, "This reverts commit 0123456789ABCDEF0123456789abcdef01234567.\n")
+	if git.Type != RevertType || git.Description != "feat(app): add login" || git.Reverts != "0123456789abcdef0123456789abcdef01234567" {
+		t.Errorf("Parse() = %+v, want git revert of 0123456", git)
+	}
+
+	conventional := Parse("revert: feat(app): add login", "This reverts commit abc1234.")
+	if conventional.Type != RevertType || conventional.Description != "feat(app): add login" || conventional.Reverts != "abc1234" {
+		t.Errorf("Parse() = %+v, want conventional revert of abc1234", conventional)
+	}
+
+	if c := Parse("fix: typo", "Mentions that this reverts commit abc1234 in prose."); c.Reverts != "" {
+		t.Errorf("expected no reverted hash outside git's revert line, got %q", c.Reverts)
+	}
+}
+
+func TestCancelReverts(t *testing.T) {
+	tests := []struct {
+		name    string
+		commits []Commit // Newest first
+		want    []bool
+	}{
+		{
+			name: "revert cancels reverted commit",
+			commits: []Commit{
+				{Hash: "cccccccc", Type: "fix"},
+				{Hash: "bbbbbbbb", Type: RevertType, Reverts: "aaaaaaa"},
+				{Hash: "aaaaaaaa", Type: "feat"},
+			},
+			want: []bool{false, true, true},
+		},
+		{
+			name: "revert of commit outside the range stays unpaired",
+			commits: []Commit{
+				{Hash: "bbbbbbbb", Type: RevertType, Reverts: "0000000"},
+				{Hash: "aaaaaaaa", Type: "feat"},
+			},
+			want: []bool{false, false},
+		},
+		{
+			name: "revert of revert re-applies the commit",
+			commits: []Commit{
+				{Hash: "cccccccc", Type: RevertType, Reverts: "bbbbbbb"},
+				{Hash: "bbbbbbbb", Type: RevertType, Reverts: "aaaaaaa"},
+				{Hash: "aaaaaaaa", Type: "feat"},
+			},
+			want: []bool{true, true, false},
+		},
+		{
+			name: "commit is only cancelled once",
+			commits: []Commit{
+				{Hash: "cccccccc", Type: RevertType, Reverts: "aaaaaaa"},
+				{Hash: "bbbbbbbb", Type: RevertType, Reverts: "aaaaaaa"},
+				{Hash: "aaaaaaaa", Type: "feat"},
+			},
+			want: []bool{false, true, true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CancelReverts(tt.commits)
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("CancelReverts() = %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestRules_UnpairedRevert(t *testing.T) {
+	revert := Parse(`Revert "feat(app): add login"`, "This reverts commit abc1234.")
+
+	if bump, _ := DefaultRules.BumpFor(revert); bump != "none" {
+		t.Errorf("BumpFor() = %q, want none without a revert rule", bump)
+	}
+	if bump, rule := (Rules{RevertType: {Bump: "patch"}}).BumpFor(revert); bump != "patch" || rule != RevertType {
+		t.Errorf("BumpFor() = (%q, %q), want (patch, revert)", bump, rule)
+	}
+	rules := Rules{"feat": {Bump: "minor"}, RevertType: {Bump: RevertedBump}}
+	if bump, rule := rules.BumpFor(revert); bump != "minor" || rule != RevertType {
+		t.Errorf("BumpFor() = (%q, %q), want (minor, revert)", bump, rule)
+	}
+	if bump, _ := rules.BumpFor(Commit{Type: RevertType, Description: "undo the login change"}); bump != "none" {
+		t.Errorf("BumpFor() = %q, want none for a non-conventional reverted subject", bump)
+	}
+}
+
+func TestParseAll(t *testing.T) {
+	body := "Adds the login screen.\n\n* feat: add login screen\n\n* fix(customerA): button colour\n\nDetails of the fix.\n- not a conventional commit\n\n* wip\n\n* feat(api)!: require tokens\n\nBREAKING CHANGE: tokens are required\n\nCo-authored-by: Jo Doe <jo@example.com>"
+	expand := Options{ExpandSquash: true}
+
+	got := ParseAll("Feature/login (#123)", body, expand)
+	want := []struct {
+		typ, scope, description string
+		breaking                bool
+	}{
+		{"feat", "", "add login screen", false},
+		{"fix", "customerA", "button colour", false},
+		{"feat", "api", "require tokens", true},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("ParseAll() returned %d commits, want %d: %+v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		if got[i].Type != w.typ || got[i].Scope != w.scope || got[i].Description != w.description || got[i].Breaking != w.breaking {
+			t.Errorf("commit %d = %+v, want %+v", i, got[i], w)
+		}
+	}
+	if !strings.Contains(got[1].Body, "- not a conventional commit") || !strings.Contains(got[1].Body, "* wip") {
+		t.Errorf("expected non-conventional lines in the preceding commit's body, got %q", got[1].Body)
+	}
+	if got[2].BreakingDescription != "tokens are required" || len(got[2].FooterValues("Co-authored-by")) != 1 {
+		t.Errorf("expected the last commit to keep its footers, got %+v", got[2].Footers)
+	}
+
+	if got := ParseAll("Feature/login (#123)", body, Options{}); len(got) != 1 || got[0].Type != "" {
+		t.Errorf("expected no expansion without ExpandSquash, got %+v", got)
+	}
+	if got := ParseAll("feat: login (#123)", body, expand); len(got) != 1 || got[0].Description != "login (#123)" {
+		t.Errorf("expected a conventional subject not to be expanded, got %+v", got)
+	}
+	if got := ParseAll("Update docs (#7)", "* typo\n* wording", expand); len(got) != 1 || got[0].Description != "Update docs (#7)" {
+		t.Errorf("expected a body without conventional commits not to be expanded, got %+v", got)
+	}
+}
+
+func TestCancelReverts_ExpandedSquash(t *testing.T) {
+	commits := []Commit{
+		{Hash: "bbbbbbbb", Type: RevertType, Reverts: "aaaaaaa"},
+		{Hash: "aaaaaaaa", Type: "feat"},
+		{Hash: "aaaaaaaa", Type: "fix"},
+	}
+	got := CancelReverts(commits)
+	if !got[0] || !got[1] || !got[2] {
+		t.Errorf("CancelReverts() = %v, want every commit of the reverted squash merge cancelled", got)
+	}
+}
+
+func TestMergeTitle(t *testing.T) {
+	tests := []struct {
+		name        string
+		subject     string
+		body        string
+		wantSubject string
+		wantBody    string
+	}{
+		{
+			name:        "github merge",
+			subject:     "Merge pull request #12 from org/feature-login",
+			body:        "feat(customerA): add login screen\n\nAdds the screen.",
+			wantSubject: "feat(customerA): add login screen",
+			wantBody:    "Adds the screen.",
+		},
+		{
+			name:        "gitlab merge",
+			subject:     "Merge branch 'feature-login' into 'main'",
+			body:        "fix: share session storage\n\nSee merge request org/app!3",
+			wantSubject: "fix: share session storage",
+			wantBody:    "See merge request org/app!3",
+		},
+		{
+			name:        "bitbucket merge",
+			subject:     "Merged in feature-login (pull request #4)",
+			body:        "feat: add login",
+			wantSubject: "feat: add login",
+		},
+		{
+			name:        "merge without title",
+			subject:     "Merge branch 'main' into feature-login",
+			wantSubject: "Merge branch 'main' into feature-login",
+		},
+		{
+			name:        "conventional merge subject",
+			subject:     "feat: add login (#12)",
+			body:        "Details.",
+			wantSubject: "feat: add login (#12)",
+			wantBody:    "Details.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subject, body := MergeTitle(tt.subject, tt.body)
+			if subject != tt.wantSubject || body != tt.wantBody {
+				t.Errorf("MergeTitle() = (%q, %q), want (%q, %q)", subject, body, tt.wantSubject, tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package commit
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Footer is a Conventional Commits footer or git trailer, e.g. "Refs: #12".
+type Footer struct {
+	Token string // e.g. "Refs", "Reviewed-by" or "BREAKING CHANGE"
+	Value string // Text after the separator; for "Token #value" footers it keeps the "#"
+}
+
+// footerRegex matches the first line of a footer: a token made of a word with "-" in
+// place of whitespace (or "BREAKING CHANGE"), followed by ": " or " #".
+var footerRegex = regexp.MustCompile(`^(BREAKING CHANGE|[A-Za-z][A-Za-z0-9-]*)(?:: | (#))(.*)$`)
+
+// issueRefTokens are the footer tokens whose values reference issues, lower-cased.
+var issueRefTokens = map[string]bool{"refs": true, "references": true, "closes": true, "fixes": true, "resolves": true}
+
+// IsBreakingToken reports whether a footer token announces a breaking change. Unlike
+// other tokens, it must be upper case, as the Conventional Commits spec requires.
+func IsBreakingToken(token string) bool {
+	return token == "BREAKING CHANGE" || token == "BREAKING-CHANGE"
+}
+
+// ParseFooters splits a commit message body into the body text and its footers. The
+// footers are the trailing paragraphs that each start with a footer line; a footer's
+// value runs until the next footer line, so it may span lines and paragraphs.
+func ParseFooters(body string) (string, []Footer) {
+	lines := strings.Split(strings.TrimRight(body, "\n"), "\n")
+
+	// Walk back over trailing paragraphs while each starts with a footer line
+	start := len(lines)
+	for end := len(lines); end > 0; {
+		first := end
+		for first > 0 && strings.TrimSpace(lines[first-1]) != "" {
+			first--
+		}
+		if first == end || !footerRegex.MatchString(lines[first]) {
+			break
+		}
+		start = first
+		end = first
+		for end > 0 && strings.TrimSpace(lines[end-1]) == "" {
+			end--
+		}
+	}
+
+	var footers []Footer
+	for _, line := range lines[start:] {
+		if m := footerRegex.FindStringSubmatch(line); m != nil {
+			footers = append(footers, Footer{Token: m[1], Value: m[2] + m[3]})
+			continue
+		}
+		last := &footers[len(footers)-1]
+		last.Value += "\n" + line
+	}
+	for i := range footers {
+		footers[i].Value = strings.TrimSpace(footers[i].Value)
+	}
+
+	return strings.TrimSpace(strings.Join(lines[:start], "\n")), footers
+}
+
+// FooterValues returns the values of the commit's footers with the given token,
+// compared case-insensitively.
+func (c Commit) FooterValues(token string) []string {
+	var values []string
+	for _, f := range c.Footers {
+		if strings.EqualFold(f.Token, token) {
+			values = append(values, f.Value)
+		}
+	}
+	return values
+}
+
+// References returns the issues referenced by the commit's Refs, Closes, Fixes and
+// Resolves footers, e.g. "#12" or "PROJ-7", in footer order.
+func (c Commit) References() []string {
+	var refs []string
+	for _, f := range c.Footers {
+		if !issueRefTokens[strings.ToLower(f.Token)] {
+			continue
+		}
+		for _, ref := range strings.FieldsFunc(f.Value, func(r rune) bool { return r == ',' || r == ' ' || r == '\n' }) {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
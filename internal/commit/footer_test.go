@@ -0,0 +1,131 @@
+package commit
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseFooters(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		wantBody    string
+		wantFooters []Footer
+	}{
+		{
+			name:     "body without footers",
+			body:     "Explains the change.\n\nMore details.",
+			wantBody: "Explains the change.\n\nMore details.",
+		},
+		{
+			name:     "git trailers",
+			body:     "Explains the change.\n\nRefs: #12\nReviewed-by: Jo Doe <jo@example.com>\n",
+			wantBody: "Explains the change.",
+			wantFooters: []Footer{
+				{Token: "Refs", Value: "#12"},
+				{Token: "Reviewed-by", Value: "Jo Doe <jo@example.com>"},
+			},
+		},
+		{
+			name:     "hash separator keeps the hash",
+			body:     "Fixes #7",
+			wantBody: "",
+			wantFooters: []Footer{
+				{Token: "Fixes", Value: "#7"},
+			},
+		},
+		{
+			name:     "breaking change value spans lines and paragraphs",
+			body:     "Context.\n\nBREAKING CHANGE: the v1 endpoints\nare gone\n\nRefs: #12",
+			wantBody: "Context.",
+			wantFooters: []Footer{
+				{Token: "BREAKING CHANGE", Value: "the v1 endpoints\nare gone"},
+				{Token: "Refs", Value: "#12"},
+			},
+		},
+		{
+			name:     "token-like line in earlier paragraph stays in body",
+			body:     "Note: this is prose.\n\nMore prose.\n\nCloses: #3",
+			wantBody: "Note: this is prose.\n\nMore prose.",
+			wantFooters: []Footer{
+				{Token: "Closes", Value: "#3"},
+			},
+		},
+		{
+			name:     "prose mentioning a breaking change",
+			body:     "There is no breaking change: callers are unaffected.",
+			wantBody: "There is no breaking change: callers are unaffected.",
+		},
+		{
+			name:     "mixed-case breaking change is not a footer",
+			body:     "Context.\n\nBreaking change: the v1 endpoints are gone",
+			wantBody: "Context.\n\nBreaking change: the v1 endpoints are gone",
+		},
+		{
+			name:     "lower-case breaking-change is an ordinary footer",
+			body:     "Context.\n\nbreaking-change: the v1 endpoints are gone",
+			wantBody: "Context.",
+			wantFooters: []Footer{
+				{Token: "breaking-change", Value: "the v1 endpoints are gone"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, footers := ParseFooters(tt.body)
+			if body != tt.wantBody {
+				t.Errorf("body = %q, want %q", body, tt.wantBody)
+			}
+			if !reflect.DeepEqual(footers, tt.wantFooters) {
+				t.Errorf("footers = %+v, want %+v", footers, tt.wantFooters)
+			}
+		})
+	}
+}
+
+func TestParse_Footers(t *testing.T) {
+	c := Parse("feat(api): add search", "Adds search.\n\nRefs: #12, #13\nReviewed-by: Jo Doe\nCloses #14")
+
+	if c.Body != "Adds search." {
+		t.Errorf("Body = %q, want %q", c.Body, "Adds search.")
+	}
+	if got := c.FooterValues("reviewed-by"); !reflect.DeepEqual(got, []string{"Jo Doe"}) {
+		t.Errorf("FooterValues(reviewed-by) = %q, want [Jo Doe]", got)
+	}
+	if got := c.References(); !reflect.DeepEqual(got, []string{"#12", "#13", "#14"}) {
+		t.Errorf("References() = %q, want [#12 #13 #14]", got)
+	}
+	if c.Breaking {
+		t.Error("expected no breaking change")
+	}
+
+	prose := Parse("fix(api): tighten validation", "This is no breaking change: old clients still work.")
+	if prose.Breaking {
+		t.Error("expected prose mentioning a breaking change not to mark the commit breaking")
+	}
+
+	for _, body := range []string{
+		"BREAKING-CHANGE: the v1 endpoints are gone",
+		"BREAKING CHANGE: the v1 endpoints are gone",
+	} {
+		if c := Parse("feat(api): drop v1", body); !c.Breaking {
+			t.Errorf("expected %q to mark the commit breaking", body)
+		}
+	}
+	for _, body := range []string{
+		"breaking change: the v1 endpoints are gone",
+		"Breaking Change: the v1 endpoints are gone",
+		"breaking-change: the v1 endpoints are gone",
+		"Breaking-Change: the v1 endpoints are gone",
+	} {
+		if c := Parse("feat(api): drop v1", body); c.Breaking {
+			t.Errorf("expected %q not to mark the commit breaking", body)
+		}
+	}
+
+	revert := Parse("revert: feat(api): add search", "Refs: 676104e, a215868")
+	if revert.Reverts != "676104e" {
+		t.Errorf("Reverts = %q, want 676104e from the Refs footer", revert.Reverts)
+	}
+}
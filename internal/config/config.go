@@ -5,20 +5,203 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 
+	"github.com/gobwas/glob"
+	"github.com/jimdowning-cyclops/semver-calc-go/internal/commit"
+	"github.com/jimdowning-cyclops/semver-calc-go/internal/version"
 	"gopkg.in/yaml.v3"
 )
 
 // Config represents the .semver.yml configuration file.
 type Config struct {
-	Products map[string]ProductConfig `yaml:"products"`
+	Products   map[string]ProductConfig `yaml:"products"`
+	Prerelease string                   `yaml:"prerelease,omitempty"` // Default pre-release channel (e.g., "rc", "beta")
+	Branches   map[string]BranchConfig  `yaml:"branches,omitempty"`   // Rules keyed by branch name or glob (e.g., "release/*")
+	Changelog  ChangelogConfig          `yaml:"changelog,omitempty"`
+	Ignore     []string                 `yaml:"ignore,omitempty"` // Globs of files that never count toward any product
+
+	ScopeSeparators    []string            `yaml:"scope_separators,omitempty"`     // Separators between multiple scopes (default "," and "/"); [] disables splitting
+	BumpRules          map[string]BumpRule `yaml:"bump_rules,omitempty"`           // Bump levels by commit type, added to or replacing the defaults
+	ExpandSquashMerges bool                `yaml:"expand_squash_merges,omitempty"` // Count the conventional commits listed in squash merge bodies individually
+	MergeStrategy      string              `yaml:"merge_strategy,omitempty"`       // Commits counted around merges: "all" (default), "first-parent" or "merge-only"
+	TagSelection       string              `yaml:"tag_selection,omitempty"`        // Last tag choice: "reachable" (default) or "highest"
+	FetchPolicy        string              `yaml:"fetch_policy,omitempty"`         // Fetching missing history: "auto" (default), "never" or "error"
+	Remote             string              `yaml:"remote,omitempty"`               // Remote to fetch history from and push tags to (default: origin)
+}
+
+// Merge strategies accepted in Config.MergeStrategy.
+const (
+	MergeStrategyAll         = "all"          // Every commit reachable since the tag, merges without files
+	MergeStrategyFirstParent = "first-parent" // Mainline commits only, merges with their files against the mainline
+	MergeStrategyMergeOnly   = "merge-only"   // Mainline merges only, parsed from their pull request titles
+)
+
+// WalksFirstParent reports whether the merge strategy reads history by first parent only.
+func (c *Config) WalksFirstParent() bool {
+	return c.MergeStrategy == MergeStrategyFirstParent || c.MergeStrategy == MergeStrategyMergeOnly
+}
+
+// BumpRule sets the bump levels of a commit type. In YAML, it is either a level
+// (e.g., "perf: patch") or a mapping with bump and breaking levels.
+type BumpRule struct {
+	Bump     string `yaml:"bump"`               // Level for commits of the type: "major", "minor", "patch" or "none"; "reverted" for revert
+	Breaking string `yaml:"breaking,omitempty"` // Level for breaking commits of the type (default "major")
 }
 
+// UnmarshalYAML decodes a bump rule from a level or a mapping.
+func (r *BumpRule) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		return value.Decode(&r.Bump)
+	}
+	type plain BumpRule // Without methods, so decoding does not recurse
+	return value.Decode((*plain)(r))
+}
+
+// ChangelogConfig controls changelog generation.
+type ChangelogConfig struct {
+	Sections []ChangelogSection `yaml:"sections,omitempty"` // Extra sections, or overrides of the defaults by title
+	Template string             `yaml:"template,omitempty"` // Path to a custom Go text/template
+}
+
+// ChangelogSection groups commit types under a changelog heading.
+type ChangelogSection struct {
+	Title string   `yaml:"title"`
+	Types []string `yaml:"types"`
+}
+
+// BranchConfig defines how versions are calculated on branches matching a pattern.
+type BranchConfig struct {
+	Prerelease string `yaml:"prerelease,omitempty"`   // Pre-release channel for matching branches
+	MaxBump    string `yaml:"max_bump,omitempty"`     // Highest bump level allowed: "major", "minor" or "patch"
+	Line       string `yaml:"line,omitempty"`         // Version line releases are pinned to, e.g. "2" or "2.4"; "branch" reads it from the branch name
+	OutOfRange string `yaml:"out_of_range,omitempty"` // Bumps that would leave the line: "downgrade" (default) or "fail"
+}
+
+// LineFromBranch is the BranchConfig.Line value that reads the version line from the last
+// segment of the branch name, e.g. "2.x" for release/2.x.
+const LineFromBranch = "branch"
+
+// VersionLine returns the version line of the rule on the given branch, or nil if the
+// rule pins no line.
+func (b BranchConfig) VersionLine(branch string) (*version.Line, error) {
+	switch b.Line {
+	case "":
+		return nil, nil
+	case LineFromBranch:
+		name := branch[strings.LastIndex(branch, "/")+1:]
+		line, err := version.ParseLine(name)
+		if err != nil {
+			return nil, fmt.Errorf("branch %q: cannot read version line from its name: %w", branch, err)
+		}
+		return &line, nil
+	default:
+		line, err := version.ParseLine(b.Line)
+		if err != nil {
+			return nil, err
+		}
+		return &line, nil
+	}
+}
+
+// Handling of bumps that would leave a branch's version line, for BranchConfig.OutOfRange.
+const (
+	OutOfRangeDowngrade = "downgrade" // Cap the bump to the highest level that stays on the line
+	OutOfRangeFail      = "fail"      // Fail the target with an error
+)
+
 // ProductConfig defines a product with its file globs and optional variants.
+// In YAML, variants are either a list of names or a mapping of names to VariantConfig.
 type ProductConfig struct {
-	Globs     []string `yaml:"globs"`
-	Variants  []string `yaml:"variants,omitempty"`
-	TagPrefix string   `yaml:"tag_prefix,omitempty"` // Custom tag prefix (default: "{product}-v")
+	Globs          []string                 `yaml:"globs"`                   // Files owned by the product; "!pattern" excludes, "{variant}" derives the variant
+	ExcludeGlobs   []string                 `yaml:"exclude_globs,omitempty"` // Files never counted for the product, even if matched by Globs
+	Variants       []string                 `yaml:"-"`                       // Variant names, in config order
+	VariantConfigs map[string]VariantConfig `yaml:"-"`                       // Per-variant settings, for variants given as a mapping
+	TagPrefix      string                   `yaml:"tag_prefix,omitempty"`    // Custom tag prefix (default: "{product}-v")
+	Changelog      string                   `yaml:"changelog,omitempty"`     // CHANGELOG.md path; "{variant}" is replaced by the variant name
+	DependsOn      []string                 `yaml:"depends_on,omitempty"`    // Products whose bumps propagate to this product
+	Propagate      string                   `yaml:"propagate,omitempty"`     // How dependency bumps propagate: "same" (default), "patch" or "none"
+	BumpRules      map[string]BumpRule      `yaml:"bump_rules,omitempty"`    // Bump levels by commit type, overriding the global rules
+
+	Scopes          []string `yaml:"scopes,omitempty"`            // Scopes naming the product itself, besides its name
+	ScopeMode       string   `yaml:"scope_mode,omitempty"`        // Handling of unknown scopes: "unscoped" (default), "strict" or "ignore"
+	ScopeIgnoreCase bool     `yaml:"scope_ignore_case,omitempty"` // Match scopes to variants and the product case-insensitively
+}
+
+// Scope modes accepted in ProductConfig.ScopeMode.
+const (
+	ScopeModeUnscoped = "unscoped" // Treat unknown scopes as unscoped (all variants)
+	ScopeModeStrict   = "strict"   // Reject commits with unknown scopes
+	ScopeModeIgnore   = "ignore"   // Drop commits with unknown scopes
+)
+
+// VariantConfig defines the optional settings of a variant.
+type VariantConfig struct {
+	Globs   []string `yaml:"globs,omitempty"`   // Files that belong to this variant only
+	Aliases []string `yaml:"aliases,omitempty"` // Scopes that select this variant, besides its name
+}
+
+// variantPlaceholder in a product glob stands for each of the product's variant names.
+const variantPlaceholder = "{variant}"
+
+// UnmarshalYAML decodes a product, accepting variants as a list of names or as a
+// mapping of names to VariantConfig.
+func (p *ProductConfig) UnmarshalYAML(value *yaml.Node) error {
+	type plain ProductConfig // Without methods, so decoding does not recurse
+	var raw struct {
+		plain    `yaml:",inline"`
+		Variants yaml.Node `yaml:"variants"`
+	}
+	if err := value.Decode(&raw); err != nil {
+		return err
+	}
+	*p = ProductConfig(raw.plain)
+
+	switch raw.Variants.Kind {
+	case 0:
+		// No variants
+	case yaml.SequenceNode:
+		if err := raw.Variants.Decode(&p.Variants); err != nil {
+			return err
+		}
+	case yaml.MappingNode:
+		p.VariantConfigs = make(map[string]VariantConfig)
+		for i := 0; i+1 < len(raw.Variants.Content); i += 2 {
+			var name string
+			if err := raw.Variants.Content[i].Decode(&name); err != nil {
+				return err
+			}
+			var vc VariantConfig
+			if err := raw.Variants.Content[i+1].Decode(&vc); err != nil {
+				return fmt.Errorf("variant %q: %w", name, err)
+			}
+			p.Variants = append(p.Variants, name)
+			p.VariantConfigs[name] = vc
+		}
+	default:
+		return fmt.Errorf("line %d: variants must be a list of names or a mapping", raw.Variants.Line)
+	}
+	return nil
+}
+
+// Propagation policies accepted in ProductConfig.Propagate.
+const (
+	PropagateSame  = "same"  // Inherit the dependency's bump level
+	PropagatePatch = "patch" // Inherit at most a patch bump
+	PropagateNone  = "none"  // Do not inherit dependency bumps
+)
+
+// PropagationCap returns the highest bump level the product inherits from its
+// dependencies, as accepted by commit.CapBump: empty for no limit.
+func (p ProductConfig) PropagationCap() string {
+	switch p.Propagate {
+	case PropagatePatch:
+		return "patch"
+	case PropagateNone:
+		return "none"
+	default:
+		return ""
+	}
 }
 
 // ProductVariant represents a specific product-variant combination.
@@ -77,12 +260,239 @@ func LoadFromDir(dir string) (*Config, error) {
 	return Load(filepath.Join(dir, ".semver.yml"))
 }
 
+// ValidateFetchPolicy checks a fetch_policy value; empty means the default, "auto".
+func ValidateFetchPolicy(policy string) error {
+	switch policy {
+	case "", "auto", "never", "error":
+		return nil
+	default:
+		return fmt.Errorf("invalid fetch_policy %q (expected auto, never or error)", policy)
+	}
+}
+
+// ValidateTagSelection checks a tag_selection value; empty means the default, "reachable".
+func ValidateTagSelection(selection string) error {
+	switch selection {
+	case "", "reachable", "highest":
+		return nil
+	default:
+		return fmt.Errorf("invalid tag_selection %q (expected reachable or highest)", selection)
+	}
+}
+
 // validate checks that the config is valid.
 func (c *Config) validate() error {
 	if len(c.Products) == 0 {
 		return fmt.Errorf("config must define at least one product")
 	}
 
+	if c.Prerelease != "" {
+		if err := version.ValidateChannel(c.Prerelease); err != nil {
+			return fmt.Errorf("invalid prerelease: %w", err)
+		}
+	}
+
+	switch c.MergeStrategy {
+	case "", MergeStrategyAll, MergeStrategyFirstParent, MergeStrategyMergeOnly:
+	default:
+		return fmt.Errorf("invalid merge_strategy %q (expected all, first-parent or merge-only)", c.MergeStrategy)
+	}
+
+	if err := ValidateFetchPolicy(c.FetchPolicy); err != nil {
+		return err
+	}
+
+	if err := ValidateTagSelection(c.TagSelection); err != nil {
+		return err
+	}
+
+	for _, sep := range c.ScopeSeparators {
+		if strings.TrimSpace(sep) == "" {
+			return fmt.Errorf("scope_separators must not contain empty or blank separators")
+		}
+	}
+
+	for i, section := range c.Changelog.Sections {
+		if section.Title == "" {
+			return fmt.Errorf("changelog section %d: title is required", i+1)
+		}
+		if len(section.Types) == 0 {
+			return fmt.Errorf("changelog section %q: at least one type is required", section.Title)
+		}
+	}
+
+	for pattern, branch := range c.Branches {
+		if _, err := glob.Compile(pattern, '/'); err != nil {
+			return fmt.Errorf("invalid branch pattern %q: %w", pattern, err)
+		}
+		if branch.Prerelease != "" {
+			if err := version.ValidateChannel(branch.Prerelease); err != nil {
+				return fmt.Errorf("branch %q: invalid prerelease: %w", pattern, err)
+			}
+		}
+		switch branch.MaxBump {
+		case "", "major", "minor", "patch":
+		default:
+			return fmt.Errorf("branch %q: invalid max_bump %q (expected major, minor or patch)", pattern, branch.MaxBump)
+		}
+		if branch.Line != "" && branch.Line != LineFromBranch {
+			if _, err := version.ParseLine(branch.Line); err != nil {
+				return fmt.Errorf("branch %q: %w", pattern, err)
+			}
+		}
+		switch branch.OutOfRange {
+		case "", OutOfRangeDowngrade, OutOfRangeFail:
+		default:
+			return fmt.Errorf("branch %q: invalid out_of_range %q (expected downgrade or fail)", pattern, branch.OutOfRange)
+		}
+	}
+
+	if err := validateBumpRules(c.BumpRules); err != nil {
+		return err
+	}
+
+	for _, name := range c.ProductNames() {
+		if err := c.validateScopes(name); err != nil {
+			return err
+		}
+		if err := validateBumpRules(c.Products[name].BumpRules); err != nil {
+			return fmt.Errorf("product %q: %w", name, err)
+		}
+	}
+
+	for _, name := range c.ProductNames() {
+		product := c.Products[name]
+		if len(product.Variants) > 0 {
+			continue
+		}
+		for _, pattern := range product.Globs {
+			if strings.Contains(pattern, variantPlaceholder) {
+				return fmt.Errorf("product %q: glob %q uses %s but the product has no variants", name, pattern, variantPlaceholder)
+			}
+		}
+	}
+
+	return c.validateDependencies()
+}
+
+// validateBumpRules checks the levels of bump rules.
+func validateBumpRules(rules map[string]BumpRule) error {
+	types := make([]string, 0, len(rules))
+	for typ := range rules {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+
+	for _, typ := range types {
+		rule := rules[typ]
+		if typ == "" {
+			return fmt.Errorf("bump_rules: commit type must not be empty")
+		}
+		switch {
+		case rule.Bump == "major", rule.Bump == "minor", rule.Bump == "patch", rule.Bump == "none":
+		case rule.Bump == commit.RevertedBump && typ == commit.RevertType:
+		default:
+			return fmt.Errorf("bump_rules %q: invalid bump %q (expected major, minor, patch or none)", typ, rule.Bump)
+		}
+		switch rule.Breaking {
+		case "", "major", "minor", "patch", "none":
+		default:
+			return fmt.Errorf("bump_rules %q: invalid breaking %q (expected major, minor, patch or none)", typ, rule.Breaking)
+		}
+	}
+	return nil
+}
+
+// validateScopes checks a product's scope mode, and that no scope selects two of its
+// variants or both a variant and the product.
+func (c *Config) validateScopes(name string) error {
+	product := c.Products[name]
+	switch product.ScopeMode {
+	case "", ScopeModeUnscoped, ScopeModeStrict, ScopeModeIgnore:
+	default:
+		return fmt.Errorf("product %q: invalid scope_mode %q (expected unscoped, strict or ignore)", name, product.ScopeMode)
+	}
+
+	owners := map[string]string{}
+	claim := func(scope, owner string) error {
+		key := scope
+		if product.ScopeIgnoreCase {
+			key = strings.ToLower(scope)
+		}
+		if other, ok := owners[key]; ok && other != owner {
+			return fmt.Errorf("product %q: scope %q selects both %s and %s", name, scope, other, owner)
+		}
+		owners[key] = owner
+		return nil
+	}
+	for _, scope := range product.Scopes {
+		if err := claim(scope, "the product"); err != nil {
+			return err
+		}
+	}
+	for _, variant := range product.Variants {
+		owner := fmt.Sprintf("variant %q", variant)
+		for _, scope := range append([]string{variant}, product.VariantConfigs[variant].Aliases...) {
+			if err := claim(scope, owner); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateDependencies checks that products only depend on known products, with a
+// valid propagation policy, and that dependencies do not form a cycle.
+func (c *Config) validateDependencies() error {
+	names := c.ProductNames()
+	for _, name := range names {
+		product := c.Products[name]
+		for _, dep := range product.DependsOn {
+			if _, ok := c.Products[dep]; !ok {
+				return fmt.Errorf("product %q depends on unknown product %q", name, dep)
+			}
+		}
+		switch product.Propagate {
+		case "", PropagateSame, PropagatePatch, PropagateNone:
+		default:
+			return fmt.Errorf("product %q: invalid propagate %q (expected same, patch or none)", name, product.Propagate)
+		}
+	}
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := map[string]int{}
+	var path []string
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visiting:
+			for i, p := range path {
+				if p == name {
+					return fmt.Errorf("dependency cycle: %s", strings.Join(append(path[i:], name), " -> "))
+				}
+			}
+		case visited:
+			return nil
+		}
+		state[name] = visiting
+		path = append(path, name)
+		for _, dep := range c.Products[name].DependsOn {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+		return nil
+	}
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -155,13 +565,129 @@ func (c *Config) HasVariants(product string) bool {
 	return len(productCfg.Variants) > 0
 }
 
-// GetGlobs returns the glob patterns for a product.
+// ScopeVariant returns the variant of a product that a commit scope selects, by the
+// variant's name or one of its aliases.
+func (c *Config) ScopeVariant(product, scope string) (string, bool) {
+	productCfg, ok := c.Products[product]
+	if !ok || scope == "" {
+		return "", false
+	}
+	for _, variant := range productCfg.Variants {
+		for _, name := range append([]string{variant}, productCfg.VariantConfigs[variant].Aliases...) {
+			if scopeEqual(productCfg, name, scope) {
+				return variant, true
+			}
+		}
+	}
+	return "", false
+}
+
+// IsProductScope reports whether a commit scope names the product itself, by its
+// name or one of its scopes.
+func (c *Config) IsProductScope(product, scope string) bool {
+	productCfg, ok := c.Products[product]
+	if !ok || scope == "" {
+		return false
+	}
+	for _, name := range append([]string{product}, productCfg.Scopes...) {
+		if scopeEqual(productCfg, name, scope) {
+			return true
+		}
+	}
+	return false
+}
+
+// scopeEqual compares a configured scope name with a commit scope.
+func scopeEqual(productCfg ProductConfig, name, scope string) bool {
+	if productCfg.ScopeIgnoreCase {
+		return strings.EqualFold(name, scope)
+	}
+	return name == scope
+}
+
+// ChangelogPath returns the changelog file path configured for a product-variant,
+// with "{variant}" replaced by the variant name. Returns "" if none is configured.
+func (c *Config) ChangelogPath(pv ProductVariant) string {
+	productCfg, ok := c.Products[pv.Product]
+	if !ok {
+		return ""
+	}
+	return strings.ReplaceAll(productCfg.Changelog, "{variant}", pv.Variant)
+}
+
+// GetGlobs returns the inclusion glob patterns shared by all variants of a product,
+// leaving out "!" negations and "{variant}" patterns.
 func (c *Config) GetGlobs(product string) ([]string, bool) {
 	productCfg, ok := c.Products[product]
 	if !ok {
 		return nil, false
 	}
-	return productCfg.Globs, true
+	var globs []string
+	for _, pattern := range productCfg.Globs {
+		if !strings.HasPrefix(pattern, "!") && !strings.Contains(pattern, variantPlaceholder) {
+			globs = append(globs, pattern)
+		}
+	}
+	return globs, true
+}
+
+// GetVariantGlobs returns the inclusion glob patterns that belong to a single variant
+// of a product, keyed by variant: the variant's own globs followed by the product's
+// "{variant}" patterns with the variant name filled in. Variants without globs are
+// left out.
+func (c *Config) GetVariantGlobs(product string) (map[string][]string, bool) {
+	productCfg, ok := c.Products[product]
+	if !ok {
+		return nil, false
+	}
+	globs := make(map[string][]string)
+	for _, variant := range productCfg.Variants {
+		patterns := append([]string{}, productCfg.VariantConfigs[variant].Globs...)
+		for _, pattern := range productCfg.Globs {
+			if !strings.HasPrefix(pattern, "!") && strings.Contains(pattern, variantPlaceholder) {
+				patterns = append(patterns, strings.ReplaceAll(pattern, variantPlaceholder, variant))
+			}
+		}
+		if len(patterns) > 0 {
+			globs[variant] = patterns
+		}
+	}
+	return globs, true
+}
+
+// GetExcludeGlobs returns the exclusion glob patterns for a product: its exclude_globs
+// followed by its "!" negated globs, without the "!". A "{variant}" in an exclusion
+// stands for any single path segment.
+func (c *Config) GetExcludeGlobs(product string) ([]string, bool) {
+	productCfg, ok := c.Products[product]
+	if !ok {
+		return nil, false
+	}
+	var globs []string
+	for _, pattern := range productCfg.ExcludeGlobs {
+		globs = append(globs, strings.ReplaceAll(pattern, variantPlaceholder, "*"))
+	}
+	for _, pattern := range productCfg.Globs {
+		if strings.HasPrefix(pattern, "!") {
+			globs = append(globs, strings.ReplaceAll(strings.TrimPrefix(pattern, "!"), variantPlaceholder, "*"))
+		}
+	}
+	return globs, true
+}
+
+// GetBumpRules returns the bump rules of a product: the defaults, overridden by type with
+// the global bump_rules and then the product's own.
+func (c *Config) GetBumpRules(product string) commit.Rules {
+	rules := commit.Rules{}
+	for typ, rule := range commit.DefaultRules {
+		rules[typ] = rule
+	}
+	for _, overrides := range []map[string]BumpRule{c.BumpRules, c.Products[product].BumpRules} {
+		for typ, rule := range overrides {
+			rules[typ] = commit.BumpRule{Bump: rule.Bump, Breaking: rule.Breaking}
+		}
+	}
+	return rules
 }
 
 // ProductNames returns all product names sorted alphabetically.
@@ -173,3 +699,69 @@ func (c *Config) ProductNames() []string {
 	sort.Strings(names)
 	return names
 }
+
+// WithDependencies returns targets followed by every product-variant of the products
+// they transitively depend on, sorted as by GetAllProductVariants and leaving out
+// those already among targets.
+func (c *Config) WithDependencies(targets []ProductVariant) []ProductVariant {
+	result := append([]ProductVariant{}, targets...)
+
+	deps := map[string]bool{}
+	var queue []string
+	for _, pv := range targets {
+		queue = append(queue, pv.Product)
+	}
+	for len(queue) > 0 {
+		product := queue[0]
+		queue = queue[1:]
+		for _, dep := range c.Products[product].DependsOn {
+			if !deps[dep] {
+				deps[dep] = true
+				queue = append(queue, dep)
+			}
+		}
+	}
+	if len(deps) == 0 {
+		return result
+	}
+
+	seen := map[ProductVariant]bool{}
+	for _, pv := range targets {
+		seen[pv] = true
+	}
+	for _, pv := range c.GetAllProductVariants() {
+		if deps[pv.Product] && !seen[pv] {
+			result = append(result, pv)
+		}
+	}
+	return result
+}
+
+// MatchBranch returns the branch rule that applies to the given branch name.
+// An exact name match wins; otherwise the longest matching glob pattern is used,
+// with ties broken alphabetically. Returns false if no rule matches.
+func (c *Config) MatchBranch(branch string) (string, BranchConfig, bool) {
+	if branch == "" {
+		return "", BranchConfig{}, false
+	}
+	if rule, ok := c.Branches[branch]; ok {
+		return branch, rule, true
+	}
+
+	var best string
+	var found bool
+	for pattern := range c.Branches {
+		g, err := glob.Compile(pattern, '/')
+		if err != nil || !g.Match(branch) {
+			continue
+		}
+		if !found || len(pattern) > len(best) || (len(pattern) == len(best) && pattern < best) {
+			best = pattern
+			found = true
+		}
+	}
+	if !found {
+		return "", BranchConfig{}, false
+	}
+	return best, c.Branches[best], true
+}
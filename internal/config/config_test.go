@@ -3,7 +3,10 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
+
+	"github.com/jimdowning-cyclops/semver-calc-go/internal/commit"
 )
 
 func TestLoad(t *testing.T) {
@@ -84,6 +87,136 @@ func TestLoad(t *testing.T) {
   mobile:
     globs: []
     variants: [customerA]
+`,
+			wantErr: false,
+		},
+		{
+			name: "config with prerelease channel",
+			content: `prerelease: rc
+products:
+  mobile:
+    globs: ["apps/mobile/**"]
+`,
+			wantErr: false,
+		},
+		{
+			name: "invalid prerelease channel",
+			content: `prerelease: rc.1
+products:
+  mobile:
+    globs: ["apps/mobile/**"]
+`,
+			wantErr:     true,
+			errContains: "invalid prerelease",
+		},
+		{
+			name: "invalid tag selection",
+			content: `tag_selection: newest
+products:
+  mobile:
+    globs: ["apps/mobile/**"]
+`,
+			wantErr:     true,
+			errContains: "invalid tag_selection",
+		},
+		{
+			name: "invalid fetch policy",
+			content: `fetch_policy: sometimes
+products:
+  mobile:
+    globs: ["apps/mobile/**"]
+`,
+			wantErr:     true,
+			errContains: "invalid fetch_policy",
+		},
+		{
+			name: "config with branch rules",
+			content: `branches:
+  main: {prerelease: beta}
+  "release/*": {prerelease: rc}
+  "hotfix/*": {max_bump: patch}
+products:
+  mobile:
+    globs: ["apps/mobile/**"]
+`,
+			wantErr: false,
+		},
+		{
+			name: "invalid branch max_bump",
+			content: `branches:
+  "hotfix/*": {max_bump: huge}
+products:
+  mobile:
+    globs: ["apps/mobile/**"]
+`,
+			wantErr:     true,
+			errContains: "invalid max_bump",
+		},
+		{
+			name: "invalid branch line",
+			content: `branches:
+  "release/*": {line: "2.4.1"}
+products:
+  mobile:
+    globs: ["apps/mobile/**"]
+`,
+			wantErr:     true,
+			errContains: "invalid version line",
+		},
+		{
+			name: "invalid branch out_of_range",
+			content: `branches:
+  "release/*": {line: branch, out_of_range: ignore}
+products:
+  mobile:
+    globs: ["apps/mobile/**"]
+`,
+			wantErr:     true,
+			errContains: "invalid out_of_range",
+		},
+		{
+			name: "invalid branch prerelease",
+			content: `branches:
+  main: {prerelease: "1"}
+products:
+  mobile:
+    globs: ["apps/mobile/**"]
+`,
+			wantErr:     true,
+			errContains: "invalid prerelease",
+		},
+		{
+			name: "config with changelog settings",
+			content: `changelog:
+  sections:
+    - title: Performance
+      types: [perf]
+products:
+  mobile:
+    globs: ["apps/mobile/**"]
+    variants: [customerA]
+    changelog: apps/mobile/{variant}/CHANGELOG.md
+`,
+			wantErr: false,
+		},
+		{
+			name: "changelog section without types",
+			content: `changelog:
+  sections:
+    - title: Performance
+products:
+  mobile:
+    globs: ["apps/mobile/**"]
+`,
+			wantErr:     true,
+			errContains: "at least one type",
+		},
+		{
+			name: "config with global ignore list",
+			content: `ignore: [".github/**", "docs/**", "**.lock", ".semver.yml"]
+products:
+  mobile:
+    variants: [customerA]
 `,
 			wantErr: false,
 		},
@@ -382,6 +515,36 @@ func TestConfig_GetGlobs(t *testing.T) {
 	}
 }
 
+func TestConfig_GetExcludeGlobs(t *testing.T) {
+	cfg, err := Parse(`
+products:
+  mobile:
+    globs: ["apps/mobile/**", "!apps/mobile/**/*.md"]
+    exclude_globs: ["apps/mobile/test/fixtures/**"]
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	globs, _ := cfg.GetGlobs("mobile")
+	if len(globs) != 1 || globs[0] != "apps/mobile/**" {
+		t.Errorf("GetGlobs() = %v, want only the inclusion glob", globs)
+	}
+
+	excludes, ok := cfg.GetExcludeGlobs("mobile")
+	if !ok {
+		t.Error("expected product to exist")
+	}
+	want := []string{"apps/mobile/test/fixtures/**", "apps/mobile/**/*.md"}
+	if len(excludes) != len(want) || excludes[0] != want[0] || excludes[1] != want[1] {
+		t.Errorf("GetExcludeGlobs() = %v, want %v", excludes, want)
+	}
+
+	if _, ok := cfg.GetExcludeGlobs("nonexistent"); ok {
+		t.Error("expected product to not exist")
+	}
+}
+
 func TestConfig_ProductNames(t *testing.T) {
 	cfg := &Config{
 		Products: map[string]ProductConfig{
@@ -405,6 +568,468 @@ func TestConfig_ProductNames(t *testing.T) {
 	}
 }
 
+func TestBranchConfig_VersionLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    BranchConfig
+		branch  string
+		want    string
+		wantErr bool
+	}{
+		{name: "no line", rule: BranchConfig{}, branch: "main"},
+		{name: "fixed line", rule: BranchConfig{Line: "2"}, branch: "maint", want: "2.x"},
+		{name: "major line from branch", rule: BranchConfig{Line: LineFromBranch}, branch: "release/2.x", want: "2.x"},
+		{name: "minor line from branch", rule: BranchConfig{Line: LineFromBranch}, branch: "release/v2.4", want: "2.4.x"},
+		{name: "branch without line", rule: BranchConfig{Line: LineFromBranch}, branch: "release/next", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line, err := tt.rule.VersionLine(tt.branch)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %v", line)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got := ""
+			if line != nil {
+				got = line.String()
+			}
+			if got != tt.want {
+				t.Errorf("VersionLine(%q) = %q, want %q", tt.branch, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfig_MatchBranch(t *testing.T) {
+	cfg := &Config{
+		Branches: map[string]BranchConfig{
+			"main":           {Prerelease: "beta"},
+			"release/*":      {Prerelease: "rc"},
+			"release/2.x":    {MaxBump: "minor"},
+			"release/*-lts":  {MaxBump: "patch"},
+			"hotfix/**":      {MaxBump: "patch"},
+			"feature/*/wip*": {Prerelease: "alpha"},
+		},
+	}
+
+	tests := []struct {
+		branch      string
+		wantPattern string
+		wantOK      bool
+	}{
+		{"main", "main", true},
+		{"release/1.4", "release/*", true},
+		{"release/2.x", "release/2.x", true},     // Exact match wins over glob
+		{"release/3-lts", "release/*-lts", true}, // Longest matching pattern wins
+		{"hotfix/a/b", "hotfix/**", true},        // ** crosses separators
+		{"release/1.4/extra", "", false},         // * does not cross separators
+		{"develop", "", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.branch, func(t *testing.T) {
+			pattern, rule, ok := cfg.MatchBranch(tt.branch)
+			if ok != tt.wantOK {
+				t.Fatalf("MatchBranch(%q) ok = %v, want %v", tt.branch, ok, tt.wantOK)
+			}
+			if pattern != tt.wantPattern {
+				t.Errorf("MatchBranch(%q) pattern = %q, want %q", tt.branch, pattern, tt.wantPattern)
+			}
+			if ok && rule != cfg.Branches[pattern] {
+				t.Errorf("MatchBranch(%q) rule = %+v, want %+v", tt.branch, rule, cfg.Branches[pattern])
+			}
+		})
+	}
+}
+
+func TestParse_Dependencies(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		errContains string
+	}{
+		{
+			name: "valid dependencies",
+			content: `products:
+  core: {}
+  common: {depends_on: [core]}
+  mobile: {depends_on: [common, core], propagate: patch}
+`,
+		},
+		{
+			name:        "unknown dependency",
+			content:     `products: {mobile: {depends_on: [core]}}`,
+			errContains: `depends on unknown product "core"`,
+		},
+		{
+			name: "invalid propagate",
+			content: `products:
+  core: {}
+  mobile: {depends_on: [core], propagate: minor}
+`,
+			errContains: "invalid propagate",
+		},
+		{
+			name:        "self dependency",
+			content:     `products: {core: {depends_on: [core]}}`,
+			errContains: "dependency cycle: core -> core",
+		},
+		{
+			name: "cycle",
+			content: `products:
+  a: {depends_on: [b]}
+  b: {depends_on: [c]}
+  c: {depends_on: [a]}
+`,
+			errContains: "dependency cycle: a -> b -> c -> a",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.content)
+			if tt.errContains == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !contains(err.Error(), tt.errContains) {
+				t.Errorf("expected error containing %q, got %v", tt.errContains, err)
+			}
+		})
+	}
+}
+
+func TestConfig_WithDependencies(t *testing.T) {
+	cfg := &Config{
+		Products: map[string]ProductConfig{
+			"mobile": {Variants: []string{"customerA", "customerB"}, DependsOn: []string{"common"}},
+			"common": {Variants: []string{"customerA"}, DependsOn: []string{"core"}},
+			"core":   {},
+			"web":    {},
+		},
+	}
+
+	targets := []ProductVariant{{Product: "mobile", Variant: "customerB"}, {Product: "core"}}
+	got := cfg.WithDependencies(targets)
+	want := []ProductVariant{
+		{Product: "mobile", Variant: "customerB"},
+		{Product: "core"},
+		{Product: "common", Variant: "customerA"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("WithDependencies() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("WithDependencies()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+
+	if got := cfg.WithDependencies([]ProductVariant{{Product: "web"}}); len(got) != 1 {
+		t.Errorf("WithDependencies(web) = %v, want only web", got)
+	}
+}
+
+func TestProductConfig_PropagationCap(t *testing.T) {
+	for propagate, want := range map[string]string{"": "", "same": "", "patch": "patch", "none": "none"} {
+		if got := (ProductConfig{Propagate: propagate}).PropagationCap(); got != want {
+			t.Errorf("PropagationCap() with propagate %q = %q, want %q", propagate, got, want)
+		}
+	}
+}
+
+func TestParse_VariantForms(t *testing.T) {
+	cfg, err := Parse(`
+products:
+  mobile:
+    globs: ["apps/mobile/shared/**", "apps/mobile/customers/{variant}/**", "!apps/mobile/customers/{variant}/tmp/**"]
+    variants:
+      customerB:
+      customerA:
+        globs: ["config/customerA/**"]
+  web:
+    globs: ["apps/web/**"]
+    variants: [customerA, customerB]
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	mobile := cfg.Products["mobile"]
+	if len(mobile.Variants) != 2 || mobile.Variants[0] != "customerB" || mobile.Variants[1] != "customerA" {
+		t.Errorf("mobile variants = %v, want [customerB customerA] in config order", mobile.Variants)
+	}
+	if web := cfg.Products["web"]; len(web.Variants) != 2 || web.VariantConfigs != nil {
+		t.Errorf("web variants = %v, configs %v; want plain list", web.Variants, web.VariantConfigs)
+	}
+
+	globs, _ := cfg.GetGlobs("mobile")
+	if len(globs) != 1 || globs[0] != "apps/mobile/shared/**" {
+		t.Errorf("GetGlobs() = %v, want only the shared glob", globs)
+	}
+
+	variantGlobs, ok := cfg.GetVariantGlobs("mobile")
+	if !ok {
+		t.Fatal("expected product to exist")
+	}
+	wantA := []string{"config/customerA/**", "apps/mobile/customers/customerA/**"}
+	if got := variantGlobs["customerA"]; len(got) != 2 || got[0] != wantA[0] || got[1] != wantA[1] {
+		t.Errorf("customerA globs = %v, want %v", got, wantA)
+	}
+	if got := variantGlobs["customerB"]; len(got) != 1 || got[0] != "apps/mobile/customers/customerB/**" {
+		t.Errorf("customerB globs = %v", got)
+	}
+	if got, _ := cfg.GetVariantGlobs("web"); len(got) != 0 {
+		t.Errorf("web variant globs = %v, want none", got)
+	}
+
+	excludes, _ := cfg.GetExcludeGlobs("mobile")
+	if len(excludes) != 1 || excludes[0] != "apps/mobile/customers/*/tmp/**" {
+		t.Errorf("GetExcludeGlobs() = %v", excludes)
+	}
+}
+
+func TestParse_VariantErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		errContains string
+	}{
+		{"placeholder without variants", `products: {app: {globs: ["apps/{variant}/**"]}}`, "has no variants"},
+		{"variants as scalar", `products: {app: {variants: customerA}}`, "list of names or a mapping"},
+		{"invalid variant config", `products: {app: {variants: {customerA: [x]}}}`, `variant "customerA"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.content)
+			if err == nil || !contains(err.Error(), tt.errContains) {
+				t.Errorf("expected error containing %q, got %v", tt.errContains, err)
+			}
+		})
+	}
+}
+
+func TestParse_Scopes(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		errContains string
+	}{
+		{"invalid scope mode", `products: {app: {scope_mode: loose}}`, "invalid scope_mode"},
+		{
+			name:        "alias shared by two variants",
+			content:     `products: {app: {variants: {a: {aliases: [x]}, b: {aliases: [x]}}}}`,
+			errContains: `scope "x" selects both`,
+		},
+		{
+			name:        "alias clashes with variant ignoring case",
+			content:     `products: {app: {scope_ignore_case: true, variants: {a: {aliases: [B]}, b: }}}`,
+			errContains: `scope "b" selects both`,
+		},
+		{
+			name:        "product scope clashes with variant",
+			content:     `products: {app: {scopes: [a], variants: [a, b]}}`,
+			errContains: `scope "a" selects both`,
+		},
+		{"blank scope separator", "scope_separators: [\",\", \" \"]\nproducts: {app: {}}", "scope_separators"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.content)
+			if err == nil || !contains(err.Error(), tt.errContains) {
+				t.Errorf("expected error containing %q, got %v", tt.errContains, err)
+			}
+		})
+	}
+}
+
+func TestParse_ScopeSeparators(t *testing.T) {
+	cfg, err := Parse("products: {app: {}}")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.ScopeSeparators != nil {
+		t.Errorf("expected nil separators by default, got %q", cfg.ScopeSeparators)
+	}
+
+	cfg, err = Parse("scope_separators: []\nproducts: {app: {}}")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.ScopeSeparators == nil || len(cfg.ScopeSeparators) != 0 {
+		t.Errorf("expected empty non-nil separators to disable splitting, got %#v", cfg.ScopeSeparators)
+	}
+}
+
+func TestParse_MergeStrategy(t *testing.T) {
+	tests := []struct {
+		strategy    string
+		firstParent bool
+		wantErr     bool
+	}{
+		{"", false, false},
+		{"all", false, false},
+		{"first-parent", true, false},
+		{"merge-only", true, false},
+		{"squash", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.strategy, func(t *testing.T) {
+			cfg, err := Parse("merge_strategy: '" + tt.strategy + "'\nproducts: {app: {}}")
+			if tt.wantErr {
+				if err == nil || !contains(err.Error(), "invalid merge_strategy") {
+					t.Errorf("expected invalid merge_strategy error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := cfg.WalksFirstParent(); got != tt.firstParent {
+				t.Errorf("WalksFirstParent() = %v, want %v", got, tt.firstParent)
+			}
+		})
+	}
+}
+
+func TestConfig_ScopeVariant(t *testing.T) {
+	cfg, err := Parse(`
+products:
+  mobile:
+    scopes: [app]
+    variants:
+      customerA: {aliases: [custA]}
+      customerB:
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if v, ok := cfg.ScopeVariant("mobile", "custA"); !ok || v != "customerA" {
+		t.Errorf("ScopeVariant(custA) = %q, %v; want customerA", v, ok)
+	}
+	if v, ok := cfg.ScopeVariant("mobile", "customerB"); !ok || v != "customerB" {
+		t.Errorf("ScopeVariant(customerB) = %q, %v; want customerB", v, ok)
+	}
+	if _, ok := cfg.ScopeVariant("mobile", "custa"); ok {
+		t.Error("expected case-sensitive alias match by default")
+	}
+	if !cfg.IsProductScope("mobile", "mobile") || !cfg.IsProductScope("mobile", "app") {
+		t.Error("expected product name and scopes to name the product")
+	}
+	if cfg.IsProductScope("mobile", "custA") || cfg.IsProductScope("mobile", "") {
+		t.Error("expected variant aliases and empty scope not to name the product")
+	}
+}
+
+func TestConfig_GetBumpRules(t *testing.T) {
+	cfg, err := Parse(`
+bump_rules:
+  perf: patch
+  revert: reverted
+  refactor: {bump: none, breaking: major}
+products:
+  app:
+    globs: ["apps/app/**"]
+    bump_rules:
+      deps: patch
+      perf: minor
+      feat: {bump: patch, breaking: minor}
+  web:
+    globs: ["apps/web/**"]
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	web := cfg.GetBumpRules("web")
+	want := commit.Rules{
+		"feat":     {Bump: "minor"},
+		"fix":      {Bump: "patch"},
+		"perf":     {Bump: "patch"},
+		"refactor": {Bump: "none", Breaking: "major"},
+		"revert":   {Bump: "reverted"},
+	}
+	if !reflect.DeepEqual(web, want) {
+		t.Errorf("GetBumpRules(web) = %v, want %v", web, want)
+	}
+
+	app := cfg.GetBumpRules("app")
+	want["deps"] = commit.BumpRule{Bump: "patch"}
+	want["perf"] = commit.BumpRule{Bump: "minor"}
+	want["feat"] = commit.BumpRule{Bump: "patch", Breaking: "minor"}
+	if !reflect.DeepEqual(app, want) {
+		t.Errorf("GetBumpRules(app) = %v, want %v", app, want)
+	}
+
+	if !reflect.DeepEqual(commit.DefaultRules, commit.Rules{"feat": {Bump: "minor"}, "fix": {Bump: "patch"}}) {
+		t.Errorf("GetBumpRules modified the default rules: %v", commit.DefaultRules)
+	}
+}
+
+func TestParse_BumpRuleErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		errContains string
+	}{
+		{"invalid bump", `{bump_rules: {perf: big}, products: {app: {}}}`, `bump_rules "perf": invalid bump "big"`},
+		{"missing bump", `{bump_rules: {perf: {breaking: major}}, products: {app: {}}}`, `invalid bump ""`},
+		{"invalid breaking", `{bump_rules: {perf: {bump: patch, breaking: huge}}, products: {app: {}}}`, `invalid breaking "huge"`},
+		{"invalid product rule", `{products: {app: {bump_rules: {feat: minr}}}}`, `product "app": bump_rules "feat"`},
+		{"reverted bump for other type", `{bump_rules: {feat: reverted}, products: {app: {}}}`, `invalid bump "reverted"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.content)
+			if err == nil || !contains(err.Error(), tt.errContains) {
+				t.Errorf("expected error containing %q, got %v", tt.errContains, err)
+			}
+		})
+	}
+}
+
+func TestConfig_ChangelogPath(t *testing.T) {
+	cfg := &Config{
+		Products: map[string]ProductConfig{
+			"mobile":     {Variants: []string{"customerA"}, Changelog: "apps/mobile/{variant}/CHANGELOG.md"},
+			"web":        {Variants: []string{"customerA"}, Changelog: "apps/web/CHANGELOG.md"},
+			"sample-app": {},
+		},
+	}
+
+	tests := []struct {
+		pv   ProductVariant
+		want string
+	}{
+		{ProductVariant{Product: "mobile", Variant: "customerA"}, "apps/mobile/customerA/CHANGELOG.md"},
+		{ProductVariant{Product: "web", Variant: "customerA"}, "apps/web/CHANGELOG.md"},
+		{ProductVariant{Product: "sample-app"}, ""},
+		{ProductVariant{Product: "missing"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pv.Product, func(t *testing.T) {
+			if got := cfg.ChangelogPath(tt.pv); got != tt.want {
+				t.Errorf("ChangelogPath(%+v) = %q, want %q", tt.pv, got, tt.want)
+			}
+		})
+	}
+}
+
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && (s == substr || len(substr) == 0 ||
 		(len(s) > 0 && len(substr) > 0 && findSubstring(s, substr)))
@@ -0,0 +1,345 @@
+package git
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/jimdowning-cyclops/semver-calc-go/internal/version"
+)
+
+// Backend provides the read-only git operations used to calculate versions.
+// Refs may be tag names, branch names, "HEAD" or commit hashes; tags are peeled
+// to the commit they point to.
+type Backend interface {
+	// Name returns the backend name as accepted by NewBackend.
+	Name() string
+	// IsRepository reports whether the working directory is inside a git repository.
+	IsRepository() bool
+	// ResolveCommit returns the hash of the commit a ref points to.
+	ResolveCommit(ref string) (string, error)
+	// RefName returns the full name of the ref r names, such as "refs/heads/main" for
+	// "main" or for HEAD on main, or an empty string if r names no ref (a commit hash or
+	// a detached HEAD).
+	RefName(r string) (string, error)
+	// Tags returns the names of all tags, or only of those reachable from merged if it
+	// is not empty.
+	Tags(merged string) ([]string, error)
+	// Log returns the commits reachable from opts.Ref (HEAD if empty) but not from since
+	// (all commits if since is empty), newest first, with their parents. Files are only
+	// populated if opts.Files is set, and are empty for merge commits unless
+	// opts.FirstParent is set.
+	Log(since string, opts LogOptions) ([]CommitInfo, error)
+	// ChangedFiles returns the files a commit changed relative to its parent, as Log
+	// lists them without opts.FirstParent.
+	ChangedFiles(hash string) ([]string, error)
+	// CountCommits counts the commits Log would return.
+	CountCommits(since string, opts LogOptions) (int, error)
+	// IsAncestor reports whether ancestor is an ancestor of (or equal to) descendant.
+	IsAncestor(ancestor, descendant string) (bool, error)
+	// IsShallow reports whether the repository is a shallow clone.
+	IsShallow() (bool, error)
+	// MergeBase returns a commit that is an ancestor of all refs.
+	MergeBase(refs ...string) (string, error)
+}
+
+// LogOptions controls which commits Backend.Log returns and how.
+type LogOptions struct {
+	Files       bool   // Populate each commit's changed files
+	FirstParent bool   // Follow only the first parent of merges, whose files are then diffed against it
+	Ref         string // Commit-ish to walk back from; HEAD if empty
+}
+
+// head returns the commit-ish to walk back from.
+func (o LogOptions) head() string {
+	if o.Ref == "" {
+		return "HEAD"
+	}
+	return o.Ref
+}
+
+// Backend names accepted by NewBackend.
+const (
+	BackendExec  = "exec"
+	BackendGoGit = "go-git"
+)
+
+// backend is the Backend used by the package-level functions.
+var backend Backend = ExecBackend{}
+
+// NewBackend returns the backend with the given name.
+func NewBackend(name string) (Backend, error) {
+	switch name {
+	case BackendExec, "":
+		return ExecBackend{}, nil
+	case BackendGoGit:
+		return &GoGitBackend{}, nil
+	default:
+		return nil, fmt.Errorf("unknown git backend %q (expected %s or %s)", name, BackendExec, BackendGoGit)
+	}
+}
+
+// SetBackend sets the Backend used by the package-level functions.
+func SetBackend(b Backend) {
+	backend = b
+}
+
+// CurrentBackend returns the Backend used by the package-level functions.
+func CurrentBackend() Backend {
+	return backend
+}
+
+// firstParent makes the package-level functions follow only the first parent of merges.
+var firstParent bool
+
+// SetFirstParent sets whether the package-level functions walk history like git log
+// --first-parent, listing the files of merge commits against their first parent.
+func SetFirstParent(enabled bool) {
+	firstParent = enabled
+}
+
+// FirstParent reports whether SetFirstParent enabled first-parent walks.
+func FirstParent() bool {
+	return firstParent
+}
+
+// ref is the commit-ish the package-level functions calculate versions at.
+var ref = "HEAD"
+
+// SetRef sets the commit-ish the package-level functions read history and tags from,
+// instead of HEAD. An empty ref resets it to HEAD.
+func SetRef(r string) {
+	if r == "" {
+		r = "HEAD"
+	}
+	ref = r
+}
+
+// Ref returns the commit-ish versions are calculated at, "HEAD" unless set with SetRef.
+func Ref() string {
+	return ref
+}
+
+// Tag selection strategies accepted by SetTagSelection.
+const (
+	TagSelectionReachable = "reachable" // Highest version tag reachable from the ref
+	TagSelectionHighest   = "highest"   // Highest version tag overall, unless a ref is set with SetRef
+)
+
+// tagSelection is how the package-level functions choose a target's last tag.
+var tagSelection = TagSelectionReachable
+
+// SetTagSelection sets how the package-level functions choose a target's last tag.
+// An empty strategy resets it to TagSelectionReachable.
+func SetTagSelection(strategy string) {
+	if strategy == "" {
+		strategy = TagSelectionReachable
+	}
+	tagSelection = strategy
+}
+
+// TagSelection returns the strategy set with SetTagSelection.
+func TagSelection() string {
+	return tagSelection
+}
+
+// versionLine restricts the package-level tag lookups to a maintenance line; nil for none.
+var versionLine *version.Line
+
+// SetVersionLine restricts FindLastTagByPrefix, FindLastReleaseTagByPrefix and
+// CheckNewTag to the tags of a version line, such as 2.x on a release/2.x branch.
+// A nil line lifts the restriction.
+func SetVersionLine(line *version.Line) {
+	versionLine = line
+}
+
+// logOptions returns the LogOptions for the package-level settings.
+func logOptions(files bool) LogOptions {
+	return LogOptions{Files: files, FirstParent: firstParent, Ref: ref}
+}
+
+// ExecBackend implements Backend by running the git command line.
+type ExecBackend struct{}
+
+// Name returns "exec".
+func (ExecBackend) Name() string {
+	return BackendExec
+}
+
+// IsRepository checks if the current directory is inside a git repository.
+func (ExecBackend) IsRepository() bool {
+	cmd := exec.Command("git", "rev-parse", "--git-dir")
+	err := cmd.Run()
+	return err == nil
+}
+
+// ResolveCommit resolves a ref to its underlying commit hash.
+func (ExecBackend) ResolveCommit(ref string) (string, error) {
+	cmd := exec.Command("git", "rev-parse", "--verify", "-q", ref+"^{commit}")
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s to commit: %w", ref, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// RefName resolves r with git rev-parse --symbolic-full-name, or git symbolic-ref for
+// HEAD so that a branch without commits yet is named too.
+func (ExecBackend) RefName(r string) (string, error) {
+	if r == "HEAD" {
+		output, err := exec.Command("git", "symbolic-ref", "-q", "HEAD").Output()
+		if err != nil {
+			// Exit code 1 means HEAD is not a symbolic ref (detached)
+			if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+				return "", nil
+			}
+			return "", fmt.Errorf("failed to resolve HEAD: %w", err)
+		}
+		return strings.TrimSpace(string(output)), nil
+	}
+
+	output, err := exec.Command("git", "rev-parse", "--symbolic-full-name", r, "--").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", r, err)
+	}
+	// Hashes print nothing, and a detached HEAD prints "HEAD"
+	name := strings.TrimSpace(strings.Split(string(output), "\n")[0])
+	if !strings.HasPrefix(name, "refs/") {
+		return "", nil
+	}
+	return name, nil
+}
+
+// Tags lists tag names with git tag, using --merged to select those reachable from merged.
+func (ExecBackend) Tags(merged string) ([]string, error) {
+	args := []string{"tag", "-l"}
+	if merged != "" {
+		args = append(args, "--merged", merged)
+	}
+	cmd := exec.Command("git", args...)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+
+	var tags []string
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if line != "" {
+			tags = append(tags, line)
+		}
+	}
+	return tags, nil
+}
+
+// Log runs git log, using ASCII record and unit separators that cannot appear in
+// ordinary commit messages to delimit commits and fields.
+func (ExecBackend) Log(since string, opts LogOptions) ([]CommitInfo, error) {
+	const commitSep = "\x1e"
+	const fieldSep = "\x1f"
+	const fileSep = "\x1f\x1f"
+
+	args := []string{"log"}
+	if since != "" {
+		args = append(args, since+".."+opts.head())
+	} else {
+		args = append(args, opts.head())
+	}
+	if opts.FirstParent {
+		// With --first-parent, -m lists a merge's files against its first parent only
+		args = append(args, "--first-parent", "-m")
+	}
+	if opts.Files {
+		// Using --name-only adds files after each commit
+		args = append(args, "--format="+commitSep+"%H"+fieldSep+"%P"+fieldSep+"%s"+fieldSep+"%b"+fileSep, "--name-only")
+	} else {
+		args = append(args, "--format=%H"+fieldSep+"%P"+fieldSep+"%s"+fieldSep+"%b"+commitSep)
+	}
+	// Refs named like files are not mistaken for paths
+	args = append(args, "--")
+
+	cmd := exec.Command("git", args...)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get git log: %w", err)
+	}
+
+	if opts.Files {
+		return parseCommitsWithFiles(string(output), commitSep, fieldSep, fileSep)
+	}
+	return parseCommits(string(output), commitSep, fieldSep), nil
+}
+
+// ChangedFiles runs git diff-tree, detecting renames as git log does.
+func (ExecBackend) ChangedFiles(hash string) ([]string, error) {
+	cmd := exec.Command("git", "diff-tree", "--no-commit-id", "--name-only", "-r", "-M", "--root", hash, "--")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get files for commit %s: %w", hash, err)
+	}
+
+	var files []string
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if line != "" {
+			files = append(files, line)
+		}
+	}
+	return files, nil
+}
+
+// CountCommits counts commits between since and opts.Ref using rev-list.
+func (ExecBackend) CountCommits(since string, opts LogOptions) (int, error) {
+	args := []string{"rev-list", "--count"}
+	if opts.FirstParent {
+		args = append(args, "--first-parent")
+	}
+	if since == "" {
+		args = append(args, opts.head(), "--")
+	} else {
+		args = append(args, since+".."+opts.head(), "--")
+	}
+	output, err := exec.Command("git", args...).Output()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count commits: %w", err)
+	}
+	var count int
+	_, err = fmt.Sscanf(strings.TrimSpace(string(output)), "%d", &count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse commit count: %w", err)
+	}
+	return count, nil
+}
+
+// IsAncestor runs git merge-base --is-ancestor.
+func (ExecBackend) IsAncestor(ancestor, descendant string) (bool, error) {
+	cmd := exec.Command("git", "merge-base", "--is-ancestor", ancestor, descendant)
+	err := cmd.Run()
+	if err != nil {
+		// Exit code 1 means not an ancestor, other errors are real errors
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+			return false, nil
+		}
+		// Exit code 128 typically means the ref doesn't exist
+		return false, nil
+	}
+	return true, nil
+}
+
+// IsShallow checks if the repository is a shallow clone.
+func (ExecBackend) IsShallow() (bool, error) {
+	cmd := exec.Command("git", "rev-parse", "--is-shallow-repository")
+	output, err := cmd.Output()
+	if err != nil {
+		return false, fmt.Errorf("failed to check for shallow repository: %w", err)
+	}
+	return strings.TrimSpace(string(output)) == "true", nil
+}
+
+// MergeBase runs git merge-base --octopus.
+func (ExecBackend) MergeBase(refs ...string) (string, error) {
+	args := append([]string{"merge-base", "--octopus"}, refs...)
+	output, err := exec.Command("git", args...).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to find merge base of %s: %w", strings.Join(refs, ", "), err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
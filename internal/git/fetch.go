@@ -0,0 +1,113 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// Fetch policies accepted by SetFetchPolicy.
+const (
+	FetchAuto  = "auto"  // Fetch missing history as needed
+	FetchNever = "never" // Never fetch; calculate from the history that is present
+	FetchError = "error" // Fail with ErrFetchRequired where history would be fetched
+)
+
+// fetchPolicy controls whether missing history is fetched from remote.
+var fetchPolicy = FetchAuto
+
+// remote is the remote missing history is fetched from.
+var remote = "origin"
+
+// SetFetchPolicy sets whether the package-level functions fetch missing history.
+// An empty policy resets it to FetchAuto.
+func SetFetchPolicy(policy string) {
+	if policy == "" {
+		policy = FetchAuto
+	}
+	fetchPolicy = policy
+}
+
+// FetchPolicy returns the policy set with SetFetchPolicy.
+func FetchPolicy() string {
+	return fetchPolicy
+}
+
+// SetRemote sets the remote missing history is fetched from. An empty name resets it
+// to "origin".
+func SetRemote(name string) {
+	if name == "" {
+		name = "origin"
+	}
+	remote = name
+}
+
+// Remote returns the remote set with SetRemote.
+func Remote() string {
+	return remote
+}
+
+// ErrFetchRequired is returned with FetchError when history would have been fetched.
+type ErrFetchRequired struct {
+	Reason string // Why history is missing, e.g. "shallow repository"
+}
+
+func (e *ErrFetchRequired) Error() string {
+	return fmt.Sprintf("git history must be fetched from %s (%s), but fetch_policy is error", remote, e.Reason)
+}
+
+// FetchRecord describes a git fetch run to complete the history.
+type FetchRecord struct {
+	Args   []string // Arguments to git, starting with "fetch"
+	Reason string   // Why history was fetched
+	Err    error    // Why the fetch failed, if it did
+}
+
+// String returns the fetch as a git command line.
+func (r FetchRecord) String() string {
+	return "git " + strings.Join(r.Args, " ")
+}
+
+var (
+	fetchesMu sync.Mutex
+	fetches   []FetchRecord
+)
+
+// Fetches returns the fetches run so far, in the order they were run.
+func Fetches() []FetchRecord {
+	fetchesMu.Lock()
+	defer fetchesMu.Unlock()
+	return append([]FetchRecord(nil), fetches...)
+}
+
+// fetchAllowed reports whether history may be fetched for reason under the fetch
+// policy, returning ErrFetchRequired with FetchError.
+func fetchAllowed(reason string) (bool, error) {
+	switch fetchPolicy {
+	case FetchNever:
+		fmt.Fprintf(os.Stderr, "[INFO] Not fetching git history (%s): fetch_policy is never\n", reason)
+		return false, nil
+	case FetchError:
+		return false, &ErrFetchRequired{Reason: reason}
+	default:
+		return true, nil
+	}
+}
+
+// runFetch runs "git fetch {remote} args..." and records it in the fetch report.
+func runFetch(reason string, args ...string) error {
+	args = append([]string{"fetch", remote}, args...)
+	err := exec.Command("git", args...).Run()
+	// Fetched history can extend commits the go-git backend has already walked
+	resetReachableCache()
+	if b, ok := backend.(*GoGitBackend); ok {
+		b.reset()
+	}
+
+	fetchesMu.Lock()
+	fetches = append(fetches, FetchRecord{Args: args, Reason: reason, Err: err})
+	fetchesMu.Unlock()
+	return err
+}
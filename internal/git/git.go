@@ -1,12 +1,16 @@
 package git
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"regexp"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/jimdowning-cyclops/semver-calc-go/internal/version"
 )
@@ -16,6 +20,7 @@ type CommitInfo struct {
 	Hash    string
 	Subject string
 	Body    string
+	Parents []string // Parent commit hashes
 	Files   []string // Files changed in this commit (only populated by GetCommitsSinceWithFiles)
 }
 
@@ -25,26 +30,24 @@ type TagInfo struct {
 	Version version.Version
 }
 
-// FindLastTag finds the most recent tag matching the pattern {product}-v{version}.
+// versionPattern matches a SemVer 2.0.0 version with optional pre-release and build metadata.
+// Stricter validation is left to version.Parse.
+const versionPattern = `\d+\.\d+\.\d+(?:-[0-9A-Za-z.-]+)?(?:\+[0-9A-Za-z.-]+)?`
+
+// FindLastTag finds the most recent tag matching the pattern {product}-v{version},
+// among the tags FindLastTagByPrefix would consider.
 // Returns the tag name, parsed version, and any error.
 // If no tag is found, returns empty string and zero version.
 func FindLastTag(product string) (string, version.Version, error) {
-	// Get all tags matching the product prefix
-	pattern := fmt.Sprintf("%s-v*", product)
-	cmd := exec.Command("git", "tag", "-l", pattern)
-	output, err := cmd.Output()
+	// Get all tags; the regex below selects the product's tags
+	tags, err := backend.Tags(mergedRef())
 	if err != nil {
-		return "", version.Zero(), fmt.Errorf("failed to list tags: %w", err)
-	}
-
-	tags := strings.Split(strings.TrimSpace(string(output)), "\n")
-	if len(tags) == 0 || (len(tags) == 1 && tags[0] == "") {
-		return "", version.Zero(), nil
+		return "", version.Zero(), err
 	}
 
 	// Parse and sort tags by version
 	var tagInfos []TagInfo
-	tagRegex := regexp.MustCompile(fmt.Sprintf(`^%s-v(\d+\.\d+\.\d+)$`, regexp.QuoteMeta(product)))
+	tagRegex := regexp.MustCompile(fmt.Sprintf(`^%s-v(%s)$`, regexp.QuoteMeta(product), versionPattern))
 
 	for _, tag := range tags {
 		matches := tagRegex.FindStringSubmatch(tag)
@@ -63,23 +66,13 @@ func FindLastTag(product string) (string, version.Version, error) {
 		return "", version.Zero(), nil
 	}
 
-	// Sort by version descending
-	sort.Slice(tagInfos, func(i, j int) bool {
-		vi, vj := tagInfos[i].Version, tagInfos[j].Version
-		if vi.Major != vj.Major {
-			return vi.Major > vj.Major
-		}
-		if vi.Minor != vj.Minor {
-			return vi.Minor > vj.Minor
-		}
-		return vi.Patch > vj.Patch
-	})
+	// Sort by version precedence descending
+	sortTagInfos(tagInfos)
 
 	return tagInfos[0].Name, tagInfos[0].Version, nil
 }
 
 // GetCommitsSince returns all commits since the given tag (or all commits if tag is empty).
-// Uses a unique separator to reliably parse multi-line commit bodies.
 // Returns empty slice if there are no commits.
 func GetCommitsSince(tag string) ([]CommitInfo, error) {
 	// First check if there are any commits at all
@@ -87,30 +80,12 @@ func GetCommitsSince(tag string) ([]CommitInfo, error) {
 		return nil, nil
 	}
 
-	// Use a unique separator that won't appear in commit messages
-	const sep = "---COMMIT-SEP---"
-	const fieldSep = "---FIELD-SEP---"
-	format := "%H" + fieldSep + "%s" + fieldSep + "%b" + sep
-
-	var cmd *exec.Cmd
-	if tag == "" {
-		cmd = exec.Command("git", "log", "--format="+format)
-	} else {
-		cmd = exec.Command("git", "log", tag+"..HEAD", "--format="+format)
-	}
-
-	output, err := cmd.Output()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get git log: %w", err)
-	}
-
-	return parseCommits(string(output), sep, fieldSep), nil
+	return backend.Log(tag, logOptions(false))
 }
 
-// hasCommits checks if the repository has any commits.
+// hasCommits checks if the repository has any commits at the ref set with SetRef.
 func hasCommits() bool {
-	cmd := exec.Command("git", "rev-parse", "HEAD")
-	err := cmd.Run()
+	_, err := backend.ResolveCommit(ref)
 	return err == nil
 }
 
@@ -130,19 +105,10 @@ func parseCommits(output, commitSep, fieldSep string) []CommitInfo {
 			continue
 		}
 
-		// Split by field separator
-		parts := strings.SplitN(raw, fieldSep, 3)
-		if len(parts) < 2 {
+		c, ok := parseCommitHeader(raw, fieldSep)
+		if !ok {
 			continue
 		}
-
-		c := CommitInfo{
-			Hash:    strings.TrimSpace(parts[0]),
-			Subject: strings.TrimSpace(parts[1]),
-		}
-		if len(parts) > 2 {
-			c.Body = strings.TrimSpace(parts[2])
-		}
 		commits = append(commits, c)
 	}
 
@@ -151,41 +117,212 @@ func parseCommits(output, commitSep, fieldSep string) []CommitInfo {
 
 // IsGitRepository checks if the current directory is inside a git repository.
 func IsGitRepository() bool {
-	cmd := exec.Command("git", "rev-parse", "--git-dir")
-	err := cmd.Run()
-	return err == nil
+	return backend.IsRepository()
+}
+
+// CurrentBranch returns the short name of the checked-out branch, or of the branch
+// named by the ref set with SetRef ("origin/release/2.x" gives "release/2.x").
+// Returns an empty string if HEAD is detached (common in CI checkouts) or the ref
+// is not a branch.
+func CurrentBranch() (string, error) {
+	return refBranch(ref)
+}
+
+// refBranch returns the branch name r refers to, without the remote name for
+// remote-tracking branches, or an empty string if r is not a branch.
+func refBranch(r string) (string, error) {
+	name, err := backend.RefName(r)
+	if err != nil {
+		return "", fmt.Errorf("failed to determine branch of %s: %w", r, err)
+	}
+	if branch, ok := strings.CutPrefix(name, "refs/heads/"); ok {
+		return branch, nil
+	}
+	if remoteBranch, ok := strings.CutPrefix(name, "refs/remotes/"); ok {
+		if _, branch, ok := strings.Cut(remoteBranch, "/"); ok {
+			return branch, nil
+		}
+	}
+	return "", nil
+}
+
+// mergedRef returns the ref tag lookups are restricted to with Backend.Tags: the ref
+// set with SetRef, or none at HEAD with TagSelectionHighest so that every tag is considered.
+func mergedRef() string {
+	if ref == "HEAD" && tagSelection == TagSelectionHighest {
+		return ""
+	}
+	return ref
+}
+
+// TagLookup is the last tag found for a tag prefix by LookupLastTag.
+type TagLookup struct {
+	Tag     string          // Empty if no tag was found
+	Version version.Version // Zero if no tag was found
+	// Unreachable lists the tags skipped because they are not reachable from the ref,
+	// though their version is higher than Tag, highest first.
+	Unreachable []string
+}
+
+// shallowMu serialises fetching history for unreachable tags in shallow clones.
+var shallowMu sync.Mutex
+
+// LookupLastTag is FindLastTagByPrefix, also reporting the higher tags it skipped as
+// unreachable. In a shallow clone, where they may only look unreachable, history is
+// fetched and the lookup repeated.
+func LookupLastTag(tagPrefix string) (TagLookup, error) {
+	return lookupLastTag(tagPrefix, false)
+}
+
+// LookupLastReleaseTag is LookupLastTag skipping pre-release tags.
+func LookupLastReleaseTag(tagPrefix string) (TagLookup, error) {
+	return lookupLastTag(tagPrefix, true)
+}
+
+// lookupLastTag implements LookupLastTag and LookupLastReleaseTag.
+func lookupLastTag(tagPrefix string, release bool) (TagLookup, error) {
+	lookup, err := findTag(tagPrefix, release)
+	if err != nil || len(lookup.Unreachable) == 0 || !IsShallowRepo() {
+		return lookup, err
+	}
+
+	shallowMu.Lock()
+	if IsShallowRepo() {
+		if _, err := EnsureFullHistoryToTag(lookup.Unreachable[0]); err != nil {
+			var required *ErrFetchRequired
+			if errors.As(err, &required) {
+				shallowMu.Unlock()
+				return TagLookup{Version: version.Zero()}, err
+			}
+			fmt.Fprintf(os.Stderr, "[WARN] Could not ensure full history: %v\n", err)
+		}
+	}
+	shallowMu.Unlock()
+	return findTag(tagPrefix, release)
+}
+
+// findTag returns the highest tag with tagPrefix in the version line set with
+// SetVersionLine that is reachable from the ref, checking tags highest first.
+func findTag(tagPrefix string, release bool) (TagLookup, error) {
+	lookup := TagLookup{Version: version.Zero()}
+	tagInfos, err := lineTagsByPrefix(tagPrefix, "")
+	if err != nil {
+		return lookup, err
+	}
+
+	merged := mergedRef()
+	for _, ti := range tagInfos {
+		if release && ti.Version.IsPrerelease() {
+			continue
+		}
+		if merged != "" {
+			reachable, err := backend.IsAncestor(ti.Name, merged)
+			if err != nil {
+				return TagLookup{Version: version.Zero()}, err
+			}
+			if !reachable {
+				lookup.Unreachable = append(lookup.Unreachable, ti.Name)
+				continue
+			}
+		}
+		lookup.Tag, lookup.Version = ti.Name, ti.Version
+		break
+	}
+	return lookup, nil
+}
+
+// lineTagsByPrefix is listTagsByPrefix keeping only the tags in the version line set
+// with SetVersionLine, if any.
+func lineTagsByPrefix(tagPrefix, merged string) ([]TagInfo, error) {
+	tagInfos, err := listTagsByPrefix(tagPrefix, merged)
+	if err != nil || versionLine == nil {
+		return tagInfos, err
+	}
+	var inLine []TagInfo
+	for _, ti := range tagInfos {
+		if versionLine.Contains(ti.Version) {
+			inLine = append(inLine, ti)
+		}
+	}
+	return inLine, nil
 }
 
 // FindLastTagByPrefix finds the most recent tag matching the given tag prefix.
 // This is useful for product-variant combinations like "mobile-customerA".
 // If tagPrefix is empty, looks for simple "v*" tags (e.g., "v1.2.3").
+// Pre-release and build metadata are recognised (e.g., "mobile-customerA-v2.0.0-rc.1"),
+// and tags are ranked by SemVer precedence, so a release outranks its pre-releases.
+// Only tags reachable from HEAD (or the ref set with SetRef) are considered, unless
+// SetTagSelection selected TagSelectionHighest; LookupLastTag also reports the higher
+// unreachable tags.
+// With a version line set by SetVersionLine, only tags in that line are considered.
 // Returns the tag name, parsed version, and any error.
 // If no tag is found, returns empty string and zero version.
 func FindLastTagByPrefix(tagPrefix string) (string, version.Version, error) {
-	// Determine pattern and regex based on prefix
-	var pattern string
+	lookup, err := LookupLastTag(tagPrefix)
+	return lookup.Tag, lookup.Version, err
+}
+
+// FindLastReleaseTagByPrefix is like FindLastTagByPrefix but skips pre-release tags.
+func FindLastReleaseTagByPrefix(tagPrefix string) (string, version.Version, error) {
+	lookup, err := LookupLastReleaseTag(tagPrefix)
+	return lookup.Tag, lookup.Version, err
+}
+
+// FindLastPrereleaseNumber finds the highest N among tags named
+// "{tagPrefix}-v{base}-{channel}.N" (or "v{base}-{channel}.N" for an empty prefix).
+// All tags are considered, even when a ref is set with SetRef, as tag names are shared
+// by every branch. Returns 0 if no such tag exists.
+func FindLastPrereleaseNumber(tagPrefix string, base version.Version, channel string) (int, error) {
+	tagInfos, err := ListTagsByPrefix(tagPrefix)
+	if err != nil {
+		return 0, err
+	}
+
+	highest := 0
+	for _, ti := range tagInfos {
+		if ti.Version.Release() != base.Release() {
+			continue
+		}
+		n, ok := strings.CutPrefix(ti.Version.Prerelease, channel+".")
+		if !ok {
+			continue
+		}
+		num, err := strconv.Atoi(n)
+		if err != nil {
+			continue // e.g. "rc.1.hotfix" is not part of the counter sequence
+		}
+		if num > highest {
+			highest = num
+		}
+	}
+	return highest, nil
+}
+
+// ListTagsByPrefix returns all version tags matching the given tag prefix,
+// sorted by SemVer precedence descending. See FindLastTagByPrefix for the naming scheme.
+func ListTagsByPrefix(tagPrefix string) ([]TagInfo, error) {
+	return listTagsByPrefix(tagPrefix, "")
+}
+
+// listTagsByPrefix is ListTagsByPrefix restricted to the tags reachable from merged,
+// if it is not empty.
+func listTagsByPrefix(tagPrefix, merged string) ([]TagInfo, error) {
+	// Determine regex based on prefix
 	var tagRegex *regexp.Regexp
 
 	if tagPrefix == "" {
 		// Simple tags: v1.2.3
-		pattern = "v*"
-		tagRegex = regexp.MustCompile(`^v(\d+\.\d+\.\d+)$`)
+		tagRegex = regexp.MustCompile(`^v(` + versionPattern + `)$`)
 	} else {
 		// Prefixed tags: product-v1.2.3
-		pattern = fmt.Sprintf("%s-v*", tagPrefix)
-		tagRegex = regexp.MustCompile(fmt.Sprintf(`^%s-v(\d+\.\d+\.\d+)$`, regexp.QuoteMeta(tagPrefix)))
+		tagRegex = regexp.MustCompile(fmt.Sprintf(`^%s-v(%s)$`, regexp.QuoteMeta(tagPrefix), versionPattern))
 	}
 
-	// Get all tags matching the pattern
-	cmd := exec.Command("git", "tag", "-l", pattern)
-	output, err := cmd.Output()
+	// Get all tags; the regex selects those with this prefix
+	tags, err := backend.Tags(merged)
 	if err != nil {
-		return "", version.Zero(), fmt.Errorf("failed to list tags: %w", err)
-	}
-
-	tags := strings.Split(strings.TrimSpace(string(output)), "\n")
-	if len(tags) == 0 || (len(tags) == 1 && tags[0] == "") {
-		return "", version.Zero(), nil
+		return nil, err
 	}
 
 	// Parse and sort tags by version
@@ -204,56 +341,53 @@ func FindLastTagByPrefix(tagPrefix string) (string, version.Version, error) {
 		tagInfos = append(tagInfos, TagInfo{Name: tag, Version: v})
 	}
 
-	if len(tagInfos) == 0 {
-		return "", version.Zero(), nil
-	}
+	// Sort by version precedence descending
+	sortTagInfos(tagInfos)
 
-	// Sort by version descending
-	sort.Slice(tagInfos, func(i, j int) bool {
-		vi, vj := tagInfos[i].Version, tagInfos[j].Version
-		if vi.Major != vj.Major {
-			return vi.Major > vj.Major
-		}
-		if vi.Minor != vj.Minor {
-			return vi.Minor > vj.Minor
+	return tagInfos, nil
+}
+
+// sortTagInfos sorts tags by SemVer precedence descending. Tags of equal precedence,
+// such as v1.0.0+a and v1.0.0+b, are ordered by name descending so the result is stable.
+func sortTagInfos(tagInfos []TagInfo) {
+	sort.SliceStable(tagInfos, func(i, j int) bool {
+		if c := tagInfos[i].Version.Compare(tagInfos[j].Version); c != 0 {
+			return c > 0
 		}
-		return vi.Patch > vj.Patch
+		return tagInfos[i].Name > tagInfos[j].Name
 	})
+}
 
-	return tagInfos[0].Name, tagInfos[0].Version, nil
+// FormatTagName returns the full tag name for a version under the given tag prefix,
+// e.g. "mobile-customerA-v1.2.3", or "v1.2.3" when tagPrefix is empty.
+func FormatTagName(tagPrefix string, v version.Version) string {
+	if tagPrefix == "" {
+		return "v" + v.String()
+	}
+	return tagPrefix + "-v" + v.String()
 }
 
-// IsTagReachableFromHead checks if a tag's commit is an ancestor of HEAD.
-// This verifies the tag is part of the current branch's history.
+// IsTagReachableFromHead checks if a tag's commit is an ancestor of HEAD, or of the
+// ref set with SetRef. This verifies the tag is part of the evaluated branch's history.
 func IsTagReachableFromHead(tag string) (bool, error) {
 	if tag == "" {
 		return true, nil
 	}
-	cmd := exec.Command("git", "merge-base", "--is-ancestor", tag, "HEAD")
-	err := cmd.Run()
-	if err != nil {
-		// Exit code 1 means not an ancestor, other errors are real errors
-		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
-			return false, nil
-		}
-		// Exit code 128 typically means the ref doesn't exist
-		return false, nil
-	}
-	return true, nil
+	return backend.IsAncestor(tag, ref)
 }
 
 // IsShallowRepo checks if the repository is a shallow clone.
 func IsShallowRepo() bool {
-	cmd := exec.Command("git", "rev-parse", "--is-shallow-repository")
-	output, err := cmd.Output()
-	if err != nil {
-		return false
-	}
-	return strings.TrimSpace(string(output)) == "true"
+	shallow, err := backend.IsShallow()
+	return err == nil && shallow
 }
 
-// EnsureFullHistoryToTag attempts to fetch full history between the tag and HEAD.
+// EnsureFullHistoryToTag attempts to fetch full history between the tag and HEAD (or
+// the ref set with SetRef) from the remote set with SetRemote.
 // This helps when the repo was cloned with incomplete history (shallow, single-branch, etc).
+// Fetches follow the policy set with SetFetchPolicy: with FetchNever nothing is fetched,
+// and with FetchError an ErrFetchRequired is returned instead of fetching. The guess that
+// one commit since the tag means missing history only triggers a fetch with FetchAuto.
 // Returns true if fetch was attempted, false if not needed.
 func EnsureFullHistoryToTag(tag string) (bool, error) {
 	if tag == "" {
@@ -264,16 +398,21 @@ func EnsureFullHistoryToTag(tag string) (bool, error) {
 
 	// Check if we're in a shallow repo
 	if IsShallowRepo() {
-		fmt.Fprintf(os.Stderr, "[INFO] Shallow repository detected, fetching full history...\n")
-		cmd := exec.Command("git", "fetch", "--unshallow")
-		if err := cmd.Run(); err != nil {
-			// Try alternative: deepen history
-			cmd = exec.Command("git", "fetch", "--deepen=2147483647")
-			if err := cmd.Run(); err != nil {
-				return true, fmt.Errorf("failed to unshallow repository: %w", err)
+		const reason = "shallow repository"
+		allowed, err := fetchAllowed(reason)
+		if err != nil {
+			return false, err
+		}
+		if allowed {
+			fmt.Fprintf(os.Stderr, "[INFO] Shallow repository detected, fetching full history...\n")
+			if err := runFetch(reason, "--unshallow"); err != nil {
+				// Try alternative: deepen history
+				if err := runFetch(reason, "--deepen=2147483647"); err != nil {
+					return true, fmt.Errorf("failed to unshallow repository: %w", err)
+				}
 			}
+			fetchAttempted = true
 		}
-		fetchAttempted = true
 	}
 
 	// Check if the tag is reachable
@@ -283,40 +422,45 @@ func EnsureFullHistoryToTag(tag string) (bool, error) {
 	}
 
 	if !reachable {
-		// Tag exists but isn't reachable - try to fetch it with history
-		fmt.Fprintf(os.Stderr, "[INFO] Tag %s not reachable from HEAD, fetching tag and its history...\n", tag)
-
-		// First fetch the tag
-		cmd := exec.Command("git", "fetch", "origin", "tag", tag, "--no-tags")
-		if err := cmd.Run(); err != nil {
-			fmt.Fprintf(os.Stderr, "[WARN] Failed to fetch tag: %v\n", err)
+		reason := fmt.Sprintf("tag %s not reachable from %s", tag, ref)
+		allowed, err := fetchAllowed(reason)
+		if err != nil {
+			return fetchAttempted, err
 		}
+		if allowed {
+			// Tag exists but isn't reachable - try to fetch it with history
+			fmt.Fprintf(os.Stderr, "[INFO] Tag %s not reachable from %s, fetching tag and its history...\n", tag, ref)
 
-		// Then try to fetch the full history for the tag (this gets merge parents etc)
-		// Use --deepen to get more history from the tag's commit
-		tagCommit, _ := GetTagCommitHash(tag)
-		if tagCommit != "" {
-			cmd = exec.Command("git", "fetch", "origin", tagCommit, "--depth=2147483647")
-			cmd.Run() // Ignore errors, this is best-effort
-		}
+			// First fetch the tag
+			if err := runFetch(reason, "tag", tag, "--no-tags"); err != nil {
+				fmt.Fprintf(os.Stderr, "[WARN] Failed to fetch tag: %v\n", err)
+			}
 
-		fetchAttempted = true
+			// Then try to fetch the full history for the tag (this gets merge parents etc)
+			// Use --deepen to get more history from the tag's commit
+			tagCommit, _ := GetTagCommitHash(tag)
+			if tagCommit != "" {
+				runFetch(reason, tagCommit, "--depth=2147483647") // Ignore errors, this is best-effort
+			}
+
+			fetchAttempted = true
+		}
 	}
 
 	// Even if tag is reachable, there might be missing merge parents
-	// Try to verify by counting commits - if count is suspiciously low, fetch more
-	commitCount, _ := CountCommitsSince(tag)
-	if commitCount <= 1 && !fetchAttempted {
+	// Try to verify by counting commits - if count is suspiciously low, fetch more.
+	// Every parent is counted, as merged branches are what a shallow fetch misses
+	commitCount, _ := backend.CountCommits(tag, LogOptions{Ref: ref})
+	if commitCount <= 1 && !fetchAttempted && fetchPolicy == FetchAuto {
 		// Very few commits since tag - this might indicate missing history
 		// Try fetching more aggressively
+		reason := fmt.Sprintf("only %d commit(s) since %s", commitCount, tag)
 		fmt.Fprintf(os.Stderr, "[INFO] Only %d commit(s) found since %s, attempting to fetch full history...\n", commitCount, tag)
 
 		// Fetch full history for the current branch
-		cmd := exec.Command("git", "fetch", "origin", "--depth=2147483647")
-		if err := cmd.Run(); err != nil {
+		if err := runFetch(reason, "--depth=2147483647"); err != nil {
 			// Try without depth option
-			cmd = exec.Command("git", "fetch", "origin")
-			cmd.Run()
+			runFetch(reason)
 		}
 		fetchAttempted = true
 	}
@@ -324,51 +468,31 @@ func EnsureFullHistoryToTag(tag string) (bool, error) {
 	return fetchAttempted, nil
 }
 
+// reportFetches reports whether any of records fetched history, or why the last one
+// failed if none did.
+func reportFetches(records []FetchRecord) {
+	var failed *FetchRecord
+	for i := range records {
+		if records[i].Err == nil {
+			fmt.Fprintf(os.Stderr, "[INFO] Fetched additional git history\n")
+			return
+		}
+		failed = &records[i]
+	}
+	if failed != nil {
+		fmt.Fprintf(os.Stderr, "[WARN] Could not fetch additional git history: %s: %v\n", failed, failed.Err)
+	}
+}
+
 // GetTagCommitHash resolves a tag to its underlying commit hash.
 func GetTagCommitHash(tag string) (string, error) {
-	cmd := exec.Command("git", "rev-parse", tag+"^{commit}")
-	output, err := cmd.Output()
-	if err != nil {
-		return "", fmt.Errorf("failed to resolve tag %s to commit: %w", tag, err)
-	}
-	return strings.TrimSpace(string(output)), nil
+	return backend.ResolveCommit(tag)
 }
 
-// CountCommitsSince counts commits between a tag and HEAD using rev-list.
+// CountCommitsSince counts commits between a tag and HEAD (or the ref set with SetRef).
 // This is more reliable than parsing git log output.
 func CountCommitsSince(tag string) (int, error) {
-	var cmd *exec.Cmd
-	if tag == "" {
-		cmd = exec.Command("git", "rev-list", "--count", "HEAD")
-	} else {
-		cmd = exec.Command("git", "rev-list", "--count", tag+"..HEAD")
-	}
-	output, err := cmd.Output()
-	if err != nil {
-		return 0, fmt.Errorf("failed to count commits: %w", err)
-	}
-	var count int
-	_, err = fmt.Sscanf(strings.TrimSpace(string(output)), "%d", &count)
-	if err != nil {
-		return 0, fmt.Errorf("failed to parse commit count: %w", err)
-	}
-	return count, nil
-}
-
-// countCommitsOnPath counts commits on the ancestry path between two refs.
-// Uses --ancestry-path to only count commits that are on a direct path.
-func countCommitsOnPath(ancestor, descendant string) (int, error) {
-	cmd := exec.Command("git", "rev-list", "--count", "--ancestry-path", ancestor+".."+descendant)
-	output, err := cmd.Output()
-	if err != nil {
-		return 0, fmt.Errorf("failed to count commits on path: %w", err)
-	}
-	var count int
-	_, err = fmt.Sscanf(strings.TrimSpace(string(output)), "%d", &count)
-	if err != nil {
-		return 0, fmt.Errorf("failed to parse commit count: %w", err)
-	}
-	return count, nil
+	return backend.CountCommits(tag, logOptions(false))
 }
 
 // ErrIncompleteHistory is returned when the git history appears incomplete.
@@ -384,77 +508,82 @@ func (e *ErrIncompleteHistory) Error() string {
 }
 
 // GetCommitsSinceWithFiles returns all commits since the given tag with their changed files.
-// Uses git log with --name-only to get file information.
+// Merge commits have no files, matching git log --name-only, unless history is walked
+// by first parent (see SetFirstParent).
 // Returns empty slice if there are no commits.
 // Automatically attempts to fetch missing history if the repo is shallow or tag is unreachable.
-// Returns ErrIncompleteHistory if the tag exists but its commit isn't reachable from HEAD after fetch attempts.
+// Returns ErrIncompleteHistory if the tag exists but its commit isn't reachable from HEAD
+// (or the ref set with SetRef) after fetch attempts.
 func GetCommitsSinceWithFiles(tag string) ([]CommitInfo, error) {
 	if !hasCommits() {
 		return nil, nil
 	}
 
-	// Attempt to ensure full history is available (handles shallow clones and missing refs)
-	if tag != "" {
-		fetched, err := EnsureFullHistoryToTag(tag)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "[WARN] Could not ensure full history: %v\n", err)
-		}
-		if fetched {
-			fmt.Fprintf(os.Stderr, "[INFO] Fetched additional git history\n")
-		}
-
-		// Verify the tag is reachable after potential fetch
-		reachable, err := IsTagReachableFromHead(tag)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "[WARN] Could not verify tag reachability: %v\n", err)
-		} else if !reachable {
-			tagCommit, _ := GetTagCommitHash(tag)
-			return nil, &ErrIncompleteHistory{
-				Tag:     tag,
-				Message: fmt.Sprintf("tag %s (commit %s) is not reachable from HEAD. This usually means the git clone has incomplete history. Try running 'git fetch --unshallow' or 'git fetch origin %s' to fetch the missing commits.", tag, tagCommit, tag),
-			}
-		}
+	if err := ensureTagHistory(tag); err != nil {
+		return nil, err
 	}
 
-	// Use unique separators
-	const commitSep = "---COMMIT-SEP---"
-	const fieldSep = "---FIELD-SEP---"
-	const fileSep = "---FILE-SEP---"
+	commits, err := backend.Log(tag, logOptions(true))
+	if err != nil {
+		return nil, err
+	}
 
-	// Format: hash, subject, body, then files on separate lines
-	// Using --name-only adds files after each commit
-	format := "%H" + fieldSep + "%s" + fieldSep + "%b" + fileSep
+	if err := verifyCommitsSince(tag, commits); err != nil {
+		return nil, err
+	}
+	return commits, nil
+}
 
-	var cmd *exec.Cmd
+// ensureTagHistory fetches missing history for tag if needed (handles shallow clones
+// and missing refs) and returns ErrIncompleteHistory if it is still not reachable from
+// the evaluated ref.
+func ensureTagHistory(tag string) error {
 	if tag == "" {
-		cmd = exec.Command("git", "log", "--format="+format, "--name-only")
-	} else {
-		cmd = exec.Command("git", "log", tag+"..HEAD", "--format="+format, "--name-only")
+		return nil
 	}
 
-	output, err := cmd.Output()
+	before := len(Fetches())
+	fetched, err := EnsureFullHistoryToTag(tag)
+	var required *ErrFetchRequired
+	if errors.As(err, &required) {
+		return err
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to get git log: %w", err)
+		fmt.Fprintf(os.Stderr, "[WARN] Could not ensure full history: %v\n", err)
+	} else if fetched {
+		reportFetches(Fetches()[before:])
 	}
 
-	commits, err := parseCommitsWithFiles(string(output), commitSep, fieldSep, fileSep)
+	// Verify the tag is reachable after potential fetch
+	reachable, err := IsTagReachableFromHead(tag)
 	if err != nil {
-		return nil, err
+		fmt.Fprintf(os.Stderr, "[WARN] Could not verify tag reachability: %v\n", err)
+	} else if !reachable {
+		tagCommit, _ := GetTagCommitHash(tag)
+		return &ErrIncompleteHistory{
+			Tag:     tag,
+			Message: fmt.Sprintf("tag %s (commit %s) is not reachable from %s. This usually means the git clone has incomplete history. Try running 'git fetch --unshallow' or 'git fetch %s %s' to fetch the missing commits.", tag, tagCommit, ref, remote, tag),
+		}
 	}
+	return nil
+}
 
+// verifyCommitsSince cross-checks commits logged since tag against the commit count
+// and ancestry, returning ErrIncompleteHistory if commits are missing.
+func verifyCommitsSince(tag string, commits []CommitInfo) error {
 	// Verify we got the expected number of commits by cross-checking with rev-list
 	expectedCount, countErr := CountCommitsSince(tag)
 	if countErr == nil && expectedCount != len(commits) {
 		// This is a significant discrepancy - history may be incomplete
 		if len(commits) < expectedCount {
-			return nil, &ErrIncompleteHistory{
+			return &ErrIncompleteHistory{
 				Tag:           tag,
 				ExpectedCount: expectedCount,
 				ActualCount:   len(commits),
 				Message: fmt.Sprintf("git history appears incomplete: expected %d commits since %s but only found %d. "+
 					"This can happen with shallow clones or incomplete fetches. "+
-					"Try running 'git fetch --unshallow' or 'git fetch --depth=0 origin' to fetch full history.",
-					expectedCount, tag, len(commits)),
+					"Try running 'git fetch --unshallow' or 'git fetch --depth=0 %s' to fetch full history.",
+					expectedCount, tag, len(commits), remote),
 			}
 		}
 	}
@@ -465,72 +594,64 @@ func GetCommitsSinceWithFiles(tag string) ([]CommitInfo, error) {
 		// Verify the oldest commit we found is actually reachable from the tag
 		// (i.e., the tag should be an ancestor of the oldest commit in our range)
 		oldestCommit := commits[len(commits)-1].Hash
-		pathCount, pathErr := countCommitsOnPath(tag, oldestCommit)
-		if pathErr == nil && pathCount == 0 {
+		connected, pathErr := backend.IsAncestor(tag, oldestCommit)
+		if pathErr == nil && !connected {
 			// There's no direct path from tag to our oldest commit - history is fragmented
 			fmt.Fprintf(os.Stderr, "[WARN] No ancestry path found from %s to commit %s, history may be fragmented\n", tag, oldestCommit[:7])
 		}
 	}
+	return nil
+}
 
-	return commits, nil
+// parseCommitHeader parses "hash{fieldSep}parents{fieldSep}subject{fieldSep}body",
+// where parents are space-separated hashes.
+func parseCommitHeader(header, fieldSep string) (CommitInfo, bool) {
+	parts := strings.SplitN(header, fieldSep, 4)
+	if len(parts) < 3 {
+		return CommitInfo{}, false
+	}
+
+	c := CommitInfo{
+		Hash:    strings.TrimSpace(parts[0]),
+		Parents: strings.Fields(parts[1]),
+		Subject: strings.TrimSpace(parts[2]),
+	}
+	if len(parts) > 3 {
+		c.Body = strings.TrimSpace(parts[3])
+	}
+	return c, true
 }
 
 // parseCommitsWithFiles parses git log output with files.
-// Format from git: hash---FIELD-SEP---subject---FIELD-SEP---body---FILE-SEP---
-// followed by file names (one per line), then empty line before next commit.
+// Format from git: {commitSep}hash{fieldSep}parents{fieldSep}subject{fieldSep}body{fileSep}
+// followed by file names (one per line). Bodies may span multiple lines.
 func parseCommitsWithFiles(output, commitSep, fieldSep, fileSep string) ([]CommitInfo, error) {
 	if output == "" {
 		return nil, nil
 	}
 
 	var commits []CommitInfo
-	lines := strings.Split(output, "\n")
-
-	var currentCommit *CommitInfo
-	var collectingFiles bool
-
-	for _, line := range lines {
-		// Check if this line contains a new commit (has the field separator)
-		if strings.Contains(line, fieldSep) {
-			// Save previous commit if exists
-			if currentCommit != nil {
-				commits = append(commits, *currentCommit)
-			}
-
-			// Parse the new commit line
-			// Remove the file separator suffix if present
-			line = strings.TrimSuffix(line, fileSep)
+	for _, raw := range strings.Split(output, commitSep) {
+		if strings.TrimSpace(raw) == "" {
+			continue
+		}
 
-			parts := strings.SplitN(line, fieldSep, 3)
-			if len(parts) < 2 {
-				currentCommit = nil
-				collectingFiles = false
-				continue
-			}
+		// Everything after the last file separator is the file list
+		header, fileList := raw, ""
+		if i := strings.LastIndex(raw, fileSep); i >= 0 {
+			header, fileList = raw[:i], raw[i+len(fileSep):]
+		}
 
-			currentCommit = &CommitInfo{
-				Hash:    strings.TrimSpace(parts[0]),
-				Subject: strings.TrimSpace(parts[1]),
-			}
-			if len(parts) > 2 {
-				currentCommit.Body = strings.TrimSpace(parts[2])
-			}
-			collectingFiles = true
+		c, ok := parseCommitHeader(header, fieldSep)
+		if !ok {
 			continue
 		}
-
-		// Collect file names
-		if collectingFiles && currentCommit != nil {
-			trimmed := strings.TrimSpace(line)
-			if trimmed != "" {
-				currentCommit.Files = append(currentCommit.Files, trimmed)
+		for _, line := range strings.Split(fileList, "\n") {
+			if trimmed := strings.TrimSpace(line); trimmed != "" {
+				c.Files = append(c.Files, trimmed)
 			}
 		}
-	}
-
-	// Don't forget the last commit
-	if currentCommit != nil {
-		commits = append(commits, *currentCommit)
+		commits = append(commits, c)
 	}
 
 	return commits, nil
@@ -538,17 +659,95 @@ func parseCommitsWithFiles(output, commitSep, fieldSep, fileSep string) ([]Commi
 
 // GetFilesChangedInCommit returns all files changed in a specific commit.
 func GetFilesChangedInCommit(hash string) ([]string, error) {
-	cmd := exec.Command("git", "diff-tree", "--no-commit-id", "--name-only", "-r", hash)
-	output, err := cmd.Output()
+	return backend.ChangedFiles(hash)
+}
+
+// TagOptions controls how CreateTag creates an annotated tag.
+type TagOptions struct {
+	Message string
+	Sign    string // "" for unsigned, "gpg" or "ssh"
+	SignKey string // Signing key passed to "git tag -u"; git's user.signingkey is used if empty
+}
+
+// TagExists checks if a tag with the given name exists.
+func TagExists(name string) bool {
+	tags, err := backend.Tags("")
+	return err == nil && slices.Contains(tags, name)
+}
+
+// CheckNewTag verifies that a tag for version v under tagPrefix can be created:
+// the tag must not already exist and v must be higher than every existing version
+// tag with that prefix (in the version line set with SetVersionLine, if any).
+func CheckNewTag(tagPrefix string, v version.Version) error {
+	name := FormatTagName(tagPrefix, v)
+	if TagExists(name) {
+		return fmt.Errorf("tag %s already exists", name)
+	}
+
+	tagInfos, err := lineTagsByPrefix(tagPrefix, "")
 	if err != nil {
-		return nil, fmt.Errorf("failed to get files for commit %s: %w", hash, err)
+		return err
+	}
+	if len(tagInfos) > 0 && !tagInfos[0].Version.LessThan(v) {
+		return fmt.Errorf("tag %s would not be higher than existing tag %s", name, tagInfos[0].Name)
 	}
+	return nil
+}
 
-	var files []string
-	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
-		if line != "" {
-			files = append(files, line)
-		}
+// CreateTag creates an annotated (and optionally signed) tag at HEAD, or at the ref set
+// with SetRef.
+func CreateTag(name string, opts TagOptions) error {
+	var args []string
+	switch opts.Sign {
+	case "":
+		args = []string{"tag", "-a"}
+	case "gpg":
+		args = []string{"-c", "gpg.format=openpgp", "tag", "-s"}
+	case "ssh":
+		args = []string{"-c", "gpg.format=ssh", "tag", "-s"}
+	default:
+		return fmt.Errorf("unknown signing format %q (expected gpg or ssh)", opts.Sign)
+	}
+	if opts.Sign != "" && opts.SignKey != "" {
+		args = append(args, "-u", opts.SignKey)
+	}
+	args = append(args, "-m", opts.Message, name, ref)
+
+	cmd := exec.Command("git", args...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to create tag %s: %w: %s", name, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// DeleteTags deletes the given local tags.
+func DeleteTags(tags []string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	cmd := exec.Command("git", append([]string{"tag", "-d"}, tags...)...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to delete tags %s: %w: %s", strings.Join(tags, ", "), err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// PushTags pushes the given tags to a remote in a single atomic push, so either all
+// of them are pushed or none.
+func PushTags(remote string, tags []string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	args := []string{"push", "--atomic", remote}
+	for _, tag := range tags {
+		args = append(args, "refs/tags/"+tag)
+	}
+
+	cmd := exec.Command("git", args...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to push tags to %s: %w: %s", remote, err, strings.TrimSpace(string(output)))
 	}
-	return files, nil
+	return nil
 }
@@ -1,9 +1,11 @@
 package git
 
 import (
+	"errors"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/jimdowning-cyclops/semver-calc-go/internal/version"
@@ -117,237 +119,1148 @@ func withDir(dir string, fn func()) {
 	fn()
 }
 
+// forEachBackend runs fn as a subtest against each Backend implementation.
+func forEachBackend(t *testing.T, fn func(t *testing.T)) {
+	for _, name := range []string{BackendExec, BackendGoGit} {
+		t.Run(name, func(t *testing.T) {
+			b, err := NewBackend(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			old := CurrentBackend()
+			SetBackend(b)
+			defer SetBackend(old)
+			fn(t)
+		})
+	}
+}
+
 func TestFindLastTag(t *testing.T) {
+	forEachBackend(t, func(t *testing.T) {
+		dir, cleanup := testRepo(t)
+		defer cleanup()
+
+		// Make initial commit (required for tags)
+		makeCommit(t, dir, "initial commit")
+
+		withDir(dir, func() {
+			// No tags yet
+			tag, v, err := FindLastTag("myproduct")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tag != "" {
+				t.Errorf("expected empty tag, got %q", tag)
+			}
+			if v != version.Zero() {
+				t.Errorf("expected zero version, got %v", v)
+			}
+
+			// Create some tags
+			makeTag(t, dir, "myproduct-v1.0.0")
+			makeCommit(t, dir, "another commit")
+			makeTag(t, dir, "myproduct-v1.1.0")
+			makeTag(t, dir, "otherproduct-v2.0.0") // Different product
+
+			// Should find latest tag for myproduct
+			tag, v, err = FindLastTag("myproduct")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tag != "myproduct-v1.1.0" {
+				t.Errorf("expected myproduct-v1.1.0, got %q", tag)
+			}
+			if v.String() != "1.1.0" {
+				t.Errorf("expected 1.1.0, got %v", v)
+			}
+
+			// Should not find tags for other product
+			tag, v, err = FindLastTag("otherproduct")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tag != "otherproduct-v2.0.0" {
+				t.Errorf("expected otherproduct-v2.0.0, got %q", tag)
+			}
+		})
+	})
+}
+
+func TestFindLastTag_IgnoresInternalTags(t *testing.T) {
+	forEachBackend(t, func(t *testing.T) {
+		dir, cleanup := testRepo(t)
+		defer cleanup()
+
+		makeCommit(t, dir, "initial commit")
+
+		withDir(dir, func() {
+			makeTag(t, dir, "myproduct-v1.0.0")
+			makeCommit(t, dir, "another commit")
+			makeTag(t, dir, "myproduct-v1.1.0_internal") // Internal tag, should be ignored
+
+			tag, v, err := FindLastTag("myproduct")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			// Should find 1.0.0, not the internal tag
+			if tag != "myproduct-v1.0.0" {
+				t.Errorf("expected myproduct-v1.0.0, got %q", tag)
+			}
+			if v.String() != "1.0.0" {
+				t.Errorf("expected 1.0.0, got %v", v)
+			}
+		})
+	})
+}
+
+func TestGetCommitsSince(t *testing.T) {
+	forEachBackend(t, func(t *testing.T) {
+		dir, cleanup := testRepo(t)
+		defer cleanup()
+
+		makeCommit(t, dir, "feat(app): first feature")
+		makeCommit(t, dir, "fix(app): first fix")
+
+		withDir(dir, func() {
+			makeTag(t, dir, "app-v1.0.0")
+
+			makeCommit(t, dir, "feat(app): second feature")
+			makeCommit(t, dir, "fix(app): second fix")
+
+			// Get commits since tag
+			commits, err := GetCommitsSince("app-v1.0.0")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(commits) != 2 {
+				t.Errorf("expected 2 commits, got %d", len(commits))
+			}
+
+			// Commits are in reverse chronological order
+			if commits[0].Subject != "fix(app): second fix" {
+				t.Errorf("unexpected first commit: %s", commits[0].Subject)
+			}
+			if commits[1].Subject != "feat(app): second feature" {
+				t.Errorf("unexpected second commit: %s", commits[1].Subject)
+			}
+		})
+	})
+}
+
+func TestGetCommitsSince_WithBody(t *testing.T) {
+	forEachBackend(t, func(t *testing.T) {
+		dir, cleanup := testRepo(t)
+		defer cleanup()
+
+		makeCommit(t, dir, "initial")
+
+		withDir(dir, func() {
+			makeTag(t, dir, "app-v1.0.0")
+
+			makeCommitWithBody(t, dir,
+				"feat(app): new feature",
+				"This is the body.\n\nBREAKING CHANGE: something broke")
+
+			commits, err := GetCommitsSince("app-v1.0.0")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(commits) != 1 {
+				t.Fatalf("expected 1 commit, got %d", len(commits))
+			}
+
+			if commits[0].Subject != "feat(app): new feature" {
+				t.Errorf("unexpected subject: %s", commits[0].Subject)
+			}
+			if commits[0].Body == "" {
+				t.Error("expected body to be captured")
+			}
+		})
+	})
+}
+
+func TestGetCommitsSince_NoTag(t *testing.T) {
+	forEachBackend(t, func(t *testing.T) {
+		dir, cleanup := testRepo(t)
+		defer cleanup()
+
+		makeCommit(t, dir, "feat(app): first")
+		makeCommit(t, dir, "feat(app): second")
+
+		withDir(dir, func() {
+			// Get all commits (no tag)
+			commits, err := GetCommitsSince("")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(commits) != 2 {
+				t.Errorf("expected 2 commits, got %d", len(commits))
+			}
+		})
+	})
+}
+
+func TestFindLastTagByPrefix_SimpleVTags(t *testing.T) {
+	forEachBackend(t, func(t *testing.T) {
+		dir, cleanup := testRepo(t)
+		defer cleanup()
+
+		makeCommit(t, dir, "initial commit")
+
+		withDir(dir, func() {
+			// Create simple v* tags (no product prefix)
+			makeTag(t, dir, "v1.0.0")
+			makeCommit(t, dir, "another commit")
+			makeTag(t, dir, "v1.1.0")
+			makeCommit(t, dir, "yet another commit")
+			makeTag(t, dir, "v2.0.0")
+
+			// Also create a prefixed tag that should be ignored
+			makeTag(t, dir, "other-v3.0.0")
+
+			// Empty prefix should find simple v* tags
+			tag, v, err := FindLastTagByPrefix("")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tag != "v2.0.0" {
+				t.Errorf("expected v2.0.0, got %q", tag)
+			}
+			if v.String() != "2.0.0" {
+				t.Errorf("expected 2.0.0, got %v", v)
+			}
+		})
+	})
+}
+
+func TestFindLastTagByPrefix_NoMatchingTags(t *testing.T) {
+	forEachBackend(t, func(t *testing.T) {
+		dir, cleanup := testRepo(t)
+		defer cleanup()
+
+		makeCommit(t, dir, "initial commit")
+
+		withDir(dir, func() {
+			// Create only prefixed tags
+			makeTag(t, dir, "product-v1.0.0")
+
+			// Empty prefix should not find prefixed tags
+			tag, v, err := FindLastTagByPrefix("")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tag != "" {
+				t.Errorf("expected empty tag, got %q", tag)
+			}
+			if v != version.Zero() {
+				t.Errorf("expected zero version, got %v", v)
+			}
+		})
+	})
+}
+
+func TestIsGitRepository(t *testing.T) {
+	forEachBackend(t, func(t *testing.T) {
+		// Test in a git repo
+		dir, cleanup := testRepo(t)
+		defer cleanup()
+
+		withDir(dir, func() {
+			if !IsGitRepository() {
+				t.Error("expected IsGitRepository to return true")
+			}
+		})
+
+		// Test in a non-git directory
+		nonGitDir, err := os.MkdirTemp("", "non-git-*")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(nonGitDir)
+
+		withDir(nonGitDir, func() {
+			if IsGitRepository() {
+				t.Error("expected IsGitRepository to return false")
+			}
+		})
+	})
+}
+
+func TestFindLastTagByPrefix_PrereleaseAndBuild(t *testing.T) {
+	forEachBackend(t, func(t *testing.T) {
+		dir, cleanup := testRepo(t)
+		defer cleanup()
+
+		makeCommit(t, dir, "initial commit")
+
+		withDir(dir, func() {
+			makeTag(t, dir, "mobile-customerA-v1.9.0")
+			makeCommit(t, dir, "another commit")
+			makeTag(t, dir, "mobile-customerA-v2.0.0-rc.1")
+			makeTag(t, dir, "mobile-customerA-v2.0.0-beta.11")
+
+			// Pre-release tags are no longer skipped
+			tag, v, err := FindLastTagByPrefix("mobile-customerA")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tag != "mobile-customerA-v2.0.0-rc.1" {
+				t.Errorf("expected mobile-customerA-v2.0.0-rc.1, got %q", tag)
+			}
+			if v.Prerelease != "rc.1" {
+				t.Errorf("expected pre-release rc.1, got %q", v.Prerelease)
+			}
+
+			// A release outranks its own pre-releases
+			makeCommit(t, dir, "release commit")
+			makeTag(t, dir, "mobile-customerA-v2.0.0")
+			tag, _, err = FindLastTagByPrefix("mobile-customerA")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tag != "mobile-customerA-v2.0.0" {
+				t.Errorf("expected mobile-customerA-v2.0.0, got %q", tag)
+			}
+
+			// Build metadata is carried on the version
+			makeTag(t, dir, "v1.4.0+build.77")
+			tag, v, err = FindLastTagByPrefix("")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tag != "v1.4.0+build.77" {
+				t.Errorf("expected v1.4.0+build.77, got %q", tag)
+			}
+			if v.Build != "build.77" {
+				t.Errorf("expected build metadata build.77, got %q", v.Build)
+			}
+		})
+	})
+}
+
+func TestFindLastTagByPrefix_EqualPrecedence(t *testing.T) {
+	forEachBackend(t, func(t *testing.T) {
+		dir, cleanup := testRepo(t)
+		defer cleanup()
+
+		makeCommit(t, dir, "initial commit")
+		makeTag(t, dir, "v1.0.0+b")
+		makeTag(t, dir, "v1.0.0+a")
+
+		withDir(dir, func() {
+			for i := 0; i < 5; i++ {
+				tag, _, err := FindLastTagByPrefix("")
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if tag != "v1.0.0+b" {
+					t.Fatalf("expected v1.0.0+b to win the tie by name, got %q", tag)
+				}
+			}
+			tags, err := ListTagsByPrefix("")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(tags) != 2 || tags[0].Name != "v1.0.0+b" || tags[1].Name != "v1.0.0+a" {
+				t.Errorf("expected [v1.0.0+b v1.0.0+a], got %+v", tags)
+			}
+		})
+	})
+}
+
+func TestFindLastReleaseTagByPrefix(t *testing.T) {
+	forEachBackend(t, func(t *testing.T) {
+		dir, cleanup := testRepo(t)
+		defer cleanup()
+
+		makeCommit(t, dir, "initial commit")
+
+		withDir(dir, func() {
+			makeTag(t, dir, "app-v1.2.0")
+			makeCommit(t, dir, "another commit")
+			makeTag(t, dir, "app-v1.3.0-rc.1")
+
+			tag, v, err := FindLastReleaseTagByPrefix("app")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tag != "app-v1.2.0" {
+				t.Errorf("expected app-v1.2.0, got %q", tag)
+			}
+			if v.String() != "1.2.0" {
+				t.Errorf("expected 1.2.0, got %v", v)
+			}
+		})
+	})
+}
+
+func TestFindLastPrereleaseNumber(t *testing.T) {
+	forEachBackend(t, func(t *testing.T) {
+		dir, cleanup := testRepo(t)
+		defer cleanup()
+
+		makeCommit(t, dir, "initial commit")
+
+		withDir(dir, func() {
+			base := version.Version{Major: 1, Minor: 3, Patch: 0}
+
+			n, err := FindLastPrereleaseNumber("app", base, "rc")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if n != 0 {
+				t.Errorf("expected 0 with no tags, got %d", n)
+			}
+
+			makeTag(t, dir, "app-v1.3.0-rc.1")
+			makeTag(t, dir, "app-v1.3.0-rc.2")
+			makeTag(t, dir, "app-v1.3.0-rc.10")
+			makeTag(t, dir, "app-v1.3.0-beta.20")         // Different channel
+			makeTag(t, dir, "app-v1.4.0-rc.30")           // Different base
+			makeTag(t, dir, "app-customerA-v1.3.0-rc.40") // Different prefix
+
+			n, err = FindLastPrereleaseNumber("app", base, "rc")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if n != 10 {
+				t.Errorf("expected 10, got %d", n)
+			}
+
+			n, err = FindLastPrereleaseNumber("app", base, "beta")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if n != 20 {
+				t.Errorf("expected 20, got %d", n)
+			}
+		})
+	})
+}
+
+func TestFormatTagName(t *testing.T) {
+	v := version.Version{Major: 1, Minor: 3, Patch: 0, Prerelease: "rc.1"}
+	if got := FormatTagName("mobile-customerA", v); got != "mobile-customerA-v1.3.0-rc.1" {
+		t.Errorf("FormatTagName() = %q, want mobile-customerA-v1.3.0-rc.1", got)
+	}
+	if got := FormatTagName("", v); got != "v1.3.0-rc.1" {
+		t.Errorf("FormatTagName() = %q, want v1.3.0-rc.1", got)
+	}
+}
+
+func TestCurrentBranch(t *testing.T) {
+	forEachBackend(t, func(t *testing.T) {
+		dir, cleanup := testRepo(t)
+		defer cleanup()
+
+		makeCommit(t, dir, "initial commit")
+
+		withDir(dir, func() {
+			if err := runGit(dir, "checkout", "-q", "-b", "release/1.x"); err != nil {
+				t.Fatalf("failed to create branch: %v", err)
+			}
+
+			branch, err := CurrentBranch()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if branch != "release/1.x" {
+				t.Errorf("expected release/1.x, got %q", branch)
+			}
+
+			// Detached HEAD has no branch
+			if err := runGit(dir, "checkout", "-q", "--detach"); err != nil {
+				t.Fatalf("failed to detach HEAD: %v", err)
+			}
+			branch, err = CurrentBranch()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if branch != "" {
+				t.Errorf("expected empty branch for detached HEAD, got %q", branch)
+			}
+		})
+	})
+}
+
+func TestCreateTag(t *testing.T) {
 	dir, cleanup := testRepo(t)
 	defer cleanup()
 
-	// Make initial commit (required for tags)
 	makeCommit(t, dir, "initial commit")
 
 	withDir(dir, func() {
-		// No tags yet
-		tag, v, err := FindLastTag("myproduct")
-		if err != nil {
-			t.Fatalf("unexpected error: %v", err)
+		if TagExists("app-v1.0.0") {
+			t.Fatal("expected tag to not exist yet")
 		}
-		if tag != "" {
-			t.Errorf("expected empty tag, got %q", tag)
+
+		if err := CreateTag("app-v1.0.0", TagOptions{Message: "Release app 1.0.0"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
 		}
-		if v != version.Zero() {
-			t.Errorf("expected zero version, got %v", v)
+		if !TagExists("app-v1.0.0") {
+			t.Fatal("expected tag to exist")
 		}
 
-		// Create some tags
-		makeTag(t, dir, "myproduct-v1.0.0")
-		makeCommit(t, dir, "another commit")
-		makeTag(t, dir, "myproduct-v1.1.0")
-		makeTag(t, dir, "otherproduct-v2.0.0") // Different product
-
-		// Should find latest tag for myproduct
-		tag, v, err = FindLastTag("myproduct")
+		// Tag must be annotated with the message
+		objType, err := runGitOutput(dir, "cat-file", "-t", "app-v1.0.0")
 		if err != nil {
-			t.Fatalf("unexpected error: %v", err)
+			t.Fatalf("failed to inspect tag: %v", err)
+		}
+		if strings.TrimSpace(objType) != "tag" {
+			t.Errorf("expected annotated tag object, got %q", strings.TrimSpace(objType))
 		}
-		if tag != "myproduct-v1.1.0" {
-			t.Errorf("expected myproduct-v1.1.0, got %q", tag)
+		msg, err := runGitOutput(dir, "tag", "-l", "--format=%(contents:subject)", "app-v1.0.0")
+		if err != nil {
+			t.Fatalf("failed to read tag message: %v", err)
 		}
-		if v.String() != "1.1.0" {
-			t.Errorf("expected 1.1.0, got %v", v)
+		if strings.TrimSpace(msg) != "Release app 1.0.0" {
+			t.Errorf("unexpected tag message %q", strings.TrimSpace(msg))
 		}
 
-		// Should not find tags for other product
-		tag, v, err = FindLastTag("otherproduct")
-		if err != nil {
-			t.Fatalf("unexpected error: %v", err)
+		// Creating the same tag again fails
+		if err := CreateTag("app-v1.0.0", TagOptions{Message: "again"}); err == nil {
+			t.Error("expected error creating duplicate tag")
 		}
-		if tag != "otherproduct-v2.0.0" {
-			t.Errorf("expected otherproduct-v2.0.0, got %q", tag)
+
+		if err := CreateTag("app-v1.0.1", TagOptions{Message: "x", Sign: "pgp"}); err == nil {
+			t.Error("expected error for unknown signing format")
 		}
 	})
 }
 
-func TestFindLastTag_IgnoresInternalTags(t *testing.T) {
+func TestCheckNewTag(t *testing.T) {
+	forEachBackend(t, func(t *testing.T) {
+		dir, cleanup := testRepo(t)
+		defer cleanup()
+
+		makeCommit(t, dir, "initial commit")
+
+		withDir(dir, func() {
+			makeTag(t, dir, "app-v1.2.0")
+			makeTag(t, dir, "app-v1.3.0-rc.1")
+
+			tests := []struct {
+				version string
+				wantErr bool
+			}{
+				{"1.3.0", false},
+				{"1.3.0-rc.2", false},
+				{"1.3.0-rc.1", true}, // Already exists
+				{"1.2.5", true},      // Lower than existing
+				{"1.3.0-beta.1", true},
+			}
+
+			for _, tt := range tests {
+				v, _ := version.Parse(tt.version)
+				err := CheckNewTag("app", v)
+				if (err != nil) != tt.wantErr {
+					t.Errorf("CheckNewTag(%s) error = %v, wantErr %v", tt.version, err, tt.wantErr)
+				}
+			}
+		})
+	})
+}
+
+func TestPushTags(t *testing.T) {
 	dir, cleanup := testRepo(t)
 	defer cleanup()
 
+	// Local bare repository acting as the remote
+	remote := t.TempDir()
+	if err := runGit(remote, "init", "--bare", "-q"); err != nil {
+		t.Fatalf("failed to init bare repo: %v", err)
+	}
+
 	makeCommit(t, dir, "initial commit")
 
 	withDir(dir, func() {
-		makeTag(t, dir, "myproduct-v1.0.0")
-		makeCommit(t, dir, "another commit")
-		makeTag(t, dir, "myproduct-v1.1.0_internal") // Internal tag, should be ignored
+		if err := runGit(dir, "remote", "add", "upstream", remote); err != nil {
+			t.Fatalf("failed to add remote: %v", err)
+		}
+		if err := runGit(dir, "push", "-q", "upstream", "HEAD:refs/heads/main"); err != nil {
+			t.Fatalf("failed to push branch: %v", err)
+		}
 
-		tag, v, err := FindLastTag("myproduct")
-		if err != nil {
+		for _, tag := range []string{"app-v1.0.0", "web-customerA-v2.0.0"} {
+			if err := CreateTag(tag, TagOptions{Message: tag}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+		makeTag(t, dir, "unpushed-v0.1.0")
+
+		if err := PushTags("upstream", []string{"app-v1.0.0", "web-customerA-v2.0.0"}); err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
-		// Should find 1.0.0, not the internal tag
-		if tag != "myproduct-v1.0.0" {
-			t.Errorf("expected myproduct-v1.0.0, got %q", tag)
+
+		out, err := runGitOutput(remote, "tag", "-l")
+		if err != nil {
+			t.Fatalf("failed to list remote tags: %v", err)
+		}
+		if got := strings.Fields(out); len(got) != 2 || got[0] != "app-v1.0.0" || got[1] != "web-customerA-v2.0.0" {
+			t.Errorf("unexpected remote tags: %v", got)
 		}
-		if v.String() != "1.0.0" {
-			t.Errorf("expected 1.0.0, got %v", v)
+
+		if err := PushTags("nonexistent", []string{"app-v1.0.0"}); err == nil {
+			t.Error("expected error pushing to unknown remote")
 		}
 	})
 }
 
-func TestGetCommitsSince(t *testing.T) {
+func TestGetCommitsSinceWithFiles_MultiLineBody(t *testing.T) {
+	forEachBackend(t, func(t *testing.T) {
+		dir, cleanup := testRepo(t)
+		defer cleanup()
+
+		makeCommit(t, dir, "initial")
+
+		withDir(dir, func() {
+			makeTag(t, dir, "app-v1.0.0")
+
+			makeCommitWithBody(t, dir,
+				"feat(app): new feature",
+				"First paragraph\nspans lines.\n\nBREAKING CHANGE: something broke")
+			makeCommit(t, dir, "fix(app): a fix")
+
+			commits, err := GetCommitsSinceWithFiles("app-v1.0.0")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(commits) != 2 {
+				t.Fatalf("expected 2 commits, got %d", len(commits))
+			}
+
+			feat := commits[1]
+			if feat.Body != "First paragraph\nspans lines.\n\nBREAKING CHANGE: something broke" {
+				t.Errorf("unexpected body: %q", feat.Body)
+			}
+			if len(feat.Files) != 1 || feat.Files[0] != "file.txt" {
+				t.Errorf("expected only file.txt, got %v", feat.Files)
+			}
+			if len(commits[0].Files) != 1 || commits[0].Files[0] != "file.txt" {
+				t.Errorf("expected only file.txt, got %v", commits[0].Files)
+			}
+		})
+	})
+}
+
+func TestBackends_Agree(t *testing.T) {
 	dir, cleanup := testRepo(t)
 	defer cleanup()
 
-	makeCommit(t, dir, "feat(app): first feature")
-	makeCommit(t, dir, "fix(app): first fix")
+	write := func(name, content string) {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	commitAll := func(message string) {
+		if err := runGit(dir, "add", "-A"); err != nil {
+			t.Fatalf("failed to git add: %v", err)
+		}
+		if err := runGit(dir, "commit", "-q", "-m", message); err != nil {
+			t.Fatalf("failed to commit: %v", err)
+		}
+	}
+
+	write("apps/web/index.js", "one")
+	write("README.md", "readme")
+	commitAll("initial")
+	if err := runGit(dir, "tag", "-a", "web-v1.0.0", "-m", "Release web 1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runGit(dir, "checkout", "-q", "-b", "feature"); err != nil {
+		t.Fatal(err)
+	}
+	write("apps/web/feature.js", "feature")
+	commitAll("feat(web): add feature\n\nBody with ---FIELD-SEP--- and ---COMMIT-SEP--- markers.")
+	if err := runGit(dir, "checkout", "-q", "-"); err != nil {
+		t.Fatal(err)
+	}
+	if err := runGit(dir, "mv", "apps/web/index.js", "apps/web/main.js"); err != nil {
+		t.Fatal(err)
+	}
+	commitAll("refactor(web): rename entry point")
+	if err := runGit(dir, "merge", "-q", "--no-ff", "feature", "-m", "Merge branch 'feature'"); err != nil {
+		t.Fatal(err)
+	}
+	write("libs/core/a.go", "a")
+	write("libs/core/b.go", "b")
+	commitAll("fix(core): two files")
+
+	headRef, err := runGitOutput(dir, "symbolic-ref", "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	withDir(dir, func() {
-		makeTag(t, dir, "app-v1.0.0")
+		results := map[string][]CommitInfo{}
+		for _, name := range []string{BackendExec, BackendGoGit} {
+			b, _ := NewBackend(name)
 
-		makeCommit(t, dir, "feat(app): second feature")
-		makeCommit(t, dir, "fix(app): second fix")
+			commits, err := b.Log("web-v1.0.0", LogOptions{Files: true})
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", name, err)
+			}
+			results[name] = commits
 
-		// Get commits since tag
-		commits, err := GetCommitsSince("app-v1.0.0")
-		if err != nil {
-			t.Fatalf("unexpected error: %v", err)
+			if n, err := b.CountCommits("web-v1.0.0", LogOptions{}); err != nil || n != 4 {
+				t.Errorf("%s: CountCommits = %d, %v; want 4", name, n, err)
+			}
+			if ok, err := b.IsAncestor("web-v1.0.0", "HEAD"); err != nil || !ok {
+				t.Errorf("%s: expected tag to be an ancestor of HEAD", name)
+			}
+			if ok, _ := b.IsAncestor("HEAD", "web-v1.0.0"); ok {
+				t.Errorf("%s: expected HEAD not to be an ancestor of tag", name)
+			}
+			if ok, _ := b.IsAncestor("missing-tag", "HEAD"); ok {
+				t.Errorf("%s: expected unknown ref not to be an ancestor", name)
+			}
+			if shallow, err := b.IsShallow(); err != nil || shallow {
+				t.Errorf("%s: IsShallow = %v, %v; want false", name, shallow, err)
+			}
+			for _, c := range commits {
+				files, err := b.ChangedFiles(c.Hash)
+				if err != nil || strings.Join(files, ",") != strings.Join(c.Files, ",") {
+					t.Errorf("%s: ChangedFiles(%s) = %v, %v; want %v", name, c.Subject, files, err, c.Files)
+				}
+			}
+			for r, want := range map[string]string{
+				"HEAD": strings.TrimSpace(headRef), "feature": "refs/heads/feature",
+				"web-v1.0.0": "refs/tags/web-v1.0.0", commits[0].Hash: "",
+			} {
+				if got, err := b.RefName(r); err != nil || got != want {
+					t.Errorf("%s: RefName(%s) = %q, %v; want %q", name, r, got, err, want)
+				}
+			}
+			if _, err := b.RefName("missing"); err == nil {
+				t.Errorf("%s: expected error for unknown ref", name)
+			}
+		}
+
+		execCommits, goGitCommits := results[BackendExec], results[BackendGoGit]
+		if len(execCommits) != len(goGitCommits) {
+			t.Fatalf("exec returned %d commits, go-git %d", len(execCommits), len(goGitCommits))
 		}
-		if len(commits) != 2 {
-			t.Errorf("expected 2 commits, got %d", len(commits))
+		for i := range execCommits {
+			e, g := execCommits[i], goGitCommits[i]
+			if e.Hash != g.Hash || e.Subject != g.Subject || e.Body != g.Body ||
+				strings.Join(e.Files, ",") != strings.Join(g.Files, ",") {
+				t.Errorf("commit %d differs:\nexec:   %+v\ngo-git: %+v", i, e, g)
+			}
 		}
 
-		// Commits are in reverse chronological order
-		if commits[0].Subject != "fix(app): second fix" {
-			t.Errorf("unexpected first commit: %s", commits[0].Subject)
+		// Spot-check the interesting cases
+		byType := map[string]CommitInfo{}
+		for _, c := range goGitCommits {
+			byType[strings.SplitN(c.Subject, ":", 2)[0]] = c
+		}
+		if files := byType["Merge branch 'feature'"].Files; len(files) != 0 {
+			t.Errorf("expected no files for merge commit, got %v", files)
 		}
-		if commits[1].Subject != "feat(app): second feature" {
-			t.Errorf("unexpected second commit: %s", commits[1].Subject)
+		if files := byType["refactor(web)"].Files; strings.Join(files, ",") != "apps/web/main.js" {
+			t.Errorf("expected renamed file under its new name, got %v", files)
+		}
+		if files := byType["fix(core)"].Files; strings.Join(files, ",") != "libs/core/a.go,libs/core/b.go" {
+			t.Errorf("unexpected files %v", files)
 		}
 	})
 }
 
-func TestGetCommitsSince_WithBody(t *testing.T) {
-	dir, cleanup := testRepo(t)
-	defer cleanup()
-
-	makeCommit(t, dir, "initial")
+func TestGetCommitsSinceWithFiles_FirstParent(t *testing.T) {
+	forEachBackend(t, func(t *testing.T) {
+		dir, cleanup := testRepo(t)
+		defer cleanup()
 
-	withDir(dir, func() {
+		makeCommit(t, dir, "initial")
 		makeTag(t, dir, "app-v1.0.0")
-
-		makeCommitWithBody(t, dir,
-			"feat(app): new feature",
-			"This is the body.\n\nBREAKING CHANGE: something broke")
-
-		commits, err := GetCommitsSince("app-v1.0.0")
-		if err != nil {
-			t.Fatalf("unexpected error: %v", err)
+		if err := runGit(dir, "checkout", "-q", "-b", "feature"); err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range []string{"a.txt", "b.txt"} {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := runGit(dir, "add", name); err != nil {
+				t.Fatal(err)
+			}
+			if err := runGit(dir, "commit", "-q", "-m", "feat: add "+name); err != nil {
+				t.Fatal(err)
+			}
 		}
-		if len(commits) != 1 {
-			t.Fatalf("expected 1 commit, got %d", len(commits))
+		if err := runGit(dir, "checkout", "-q", "-"); err != nil {
+			t.Fatal(err)
 		}
+		makeCommit(t, dir, "fix: on mainline")
+		if err := runGit(dir, "merge", "-q", "--no-ff", "feature", "-m", "Merge pull request #1 from org/feature", "-m", "feat: add files"); err != nil {
+			t.Fatal(err)
+		}
+
+		SetFirstParent(true)
+		defer SetFirstParent(false)
+		withDir(dir, func() {
+			commits, err := GetCommitsSinceWithFiles("app-v1.0.0")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(commits) != 2 {
+				t.Fatalf("expected merge and mainline commit, got %+v", commits)
+			}
+			merge := commits[0]
+			if merge.Subject != "Merge pull request #1 from org/feature" || len(merge.Parents) != 2 {
+				t.Errorf("expected the merge first, got %+v", merge)
+			}
+			if strings.Join(merge.Files, ",") != "a.txt,b.txt" {
+				t.Errorf("expected merge files against its first parent, got %v", merge.Files)
+			}
+			if commits[1].Subject != "fix: on mainline" {
+				t.Errorf("expected the mainline commit, got %+v", commits[1])
+			}
+
+			h, err := LoadHistory([]string{"app-v1.0.0"})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if since, err := h.CommitsSince("app-v1.0.0"); err != nil || len(since) != 2 {
+				t.Errorf("CommitsSince() = %d commits, %v; want 2", len(since), err)
+			}
+		})
+	})
+}
+
+func TestHistory_CommitsSince_FirstParentMerge(t *testing.T) {
+	forEachBackend(t, func(t *testing.T) {
+		dir, cleanup := testRepo(t)
+		defer cleanup()
 
-		if commits[0].Subject != "feat(app): new feature" {
-			t.Errorf("unexpected subject: %s", commits[0].Subject)
+		makeCommit(t, dir, "initial")
+		makeTag(t, dir, "app-v1.0.0")
+		makeCommit(t, dir, "fix: main one")
+		makeTag(t, dir, "web-v1.0.0")
+		if err := runGit(dir, "checkout", "-q", "-b", "feature"); err != nil {
+			t.Fatal(err)
+		}
+		makeCommit(t, dir, "feat: side one")
+		makeTag(t, dir, "lib-v1.0.0")
+		makeCommit(t, dir, "feat: side two")
+		if err := runGit(dir, "checkout", "-q", "-"); err != nil {
+			t.Fatal(err)
 		}
-		if commits[0].Body == "" {
-			t.Error("expected body to be captured")
+		makeCommit(t, dir, "fix: main two")
+		if err := runGit(dir, "merge", "-q", "--no-ff", "-s", "ours", "feature", "-m", "Merge feature"); err != nil {
+			t.Fatal(err)
 		}
+		makeCommit(t, dir, "fix: main three")
+
+		SetFirstParent(true)
+		defer SetFirstParent(false)
+		withDir(dir, func() {
+			tags := []string{"app-v1.0.0", "web-v1.0.0", "lib-v1.0.0"}
+			h, err := LoadHistory(tags)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for tag, want := range map[string]string{
+				"app-v1.0.0": "fix: main three,Merge feature,fix: main two,fix: main one",
+				"web-v1.0.0": "fix: main three,Merge feature,fix: main two",
+				// Up to where the tag's branch forked from the mainline, as git log --first-parent
+				"lib-v1.0.0": "fix: main three,Merge feature,fix: main two",
+			} {
+				commits, err := h.CommitsSince(tag)
+				if err != nil {
+					t.Fatalf("%s: unexpected error: %v", tag, err)
+				}
+				var subjects []string
+				for _, c := range commits {
+					subjects = append(subjects, c.Subject)
+				}
+				if got := strings.Join(subjects, ","); got != want {
+					t.Errorf("CommitsSince(%s) = %s, want %s", tag, got, want)
+				}
+				out, err := runGitOutput(dir, "log", "--first-parent", "--format=%s", tag+"..HEAD")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if gitLog := strings.ReplaceAll(strings.TrimSpace(out), "\n", ","); gitLog != want {
+					t.Errorf("%s: git log --first-parent gives %s, want %s", tag, gitLog, want)
+				}
+			}
+		})
 	})
 }
 
-func TestGetCommitsSince_NoTag(t *testing.T) {
-	dir, cleanup := testRepo(t)
-	defer cleanup()
+func TestSetRef(t *testing.T) {
+	forEachBackend(t, func(t *testing.T) {
+		dir, cleanup := testRepo(t)
+		defer cleanup()
 
-	makeCommit(t, dir, "feat(app): first")
-	makeCommit(t, dir, "feat(app): second")
+		makeCommit(t, dir, "initial")
+		makeTag(t, dir, "app-v1.0.0")
+		makeCommit(t, dir, "feat: at ref")
+		if err := runGit(dir, "branch", "release/1.x"); err != nil {
+			t.Fatal(err)
+		}
+		makeCommit(t, dir, "fix: after ref")
+		makeTag(t, dir, "app-v1.1.0")
 
-	withDir(dir, func() {
-		// Get all commits (no tag)
-		commits, err := GetCommitsSince("")
-		if err != nil {
-			t.Fatalf("unexpected error: %v", err)
+		SetRef("release/1.x")
+		defer SetRef("")
+		withDir(dir, func() {
+			tag, v, err := FindLastTagByPrefix("app")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tag != "app-v1.0.0" || v.String() != "1.0.0" {
+				t.Errorf("expected app-v1.0.0 reachable from the ref, got %s (%s)", tag, v)
+			}
+			if tags, _ := ListTagsByPrefix("app"); len(tags) != 2 {
+				t.Errorf("expected ListTagsByPrefix to list every tag, got %+v", tags)
+			}
+
+			commits, err := GetCommitsSinceWithFiles(tag)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(commits) != 1 || commits[0].Subject != "feat: at ref" {
+				t.Errorf("expected only the commit at the ref, got %+v", commits)
+			}
+			if n, err := CountCommitsSince(""); err != nil || n != 2 {
+				t.Errorf("CountCommitsSince(\"\") = %d, %v; want 2", n, err)
+			}
+
+			h, err := LoadHistory([]string{tag})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if since, err := h.CommitsSince(tag); err != nil || len(since) != 1 {
+				t.Errorf("CommitsSince() = %d commits, %v; want 1", len(since), err)
+			}
+
+			branch, err := CurrentBranch()
+			if err != nil || branch != "release/1.x" {
+				t.Errorf("CurrentBranch() = %q, %v; want release/1.x", branch, err)
+			}
+		})
+	})
+}
+
+func TestFindLastTagByPrefix_Reachable(t *testing.T) {
+	forEachBackend(t, func(t *testing.T) {
+		dir, cleanup := testRepo(t)
+		defer cleanup()
+
+		makeCommit(t, dir, "initial")
+		makeTag(t, dir, "app-v2.4.0")
+		if err := runGit(dir, "checkout", "-q", "-b", "next"); err != nil {
+			t.Fatal(err)
 		}
-		if len(commits) != 2 {
-			t.Errorf("expected 2 commits, got %d", len(commits))
+		makeCommit(t, dir, "feat!: next major")
+		makeTag(t, dir, "app-v3.0.0")
+		if err := runGit(dir, "checkout", "-q", "-"); err != nil {
+			t.Fatal(err)
 		}
+		makeCommit(t, dir, "fix: maintenance")
+
+		withDir(dir, func() {
+			lookup, err := LookupLastTag("app")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if lookup.Tag != "app-v2.4.0" {
+				t.Errorf("expected reachable app-v2.4.0, got %q", lookup.Tag)
+			}
+			if ignored := lookup.Unreachable; strings.Join(ignored, " ") != "app-v3.0.0" {
+				t.Errorf("expected app-v3.0.0 to be ignored, got %v", ignored)
+			}
+			if tag, _, _ := FindLastReleaseTagByPrefix("app"); tag != "app-v2.4.0" {
+				t.Errorf("expected reachable release app-v2.4.0, got %q", tag)
+			}
+
+			SetTagSelection(TagSelectionHighest)
+			defer SetTagSelection("")
+			lookup, err = LookupLastTag("app")
+			if err != nil || lookup.Tag != "app-v3.0.0" {
+				t.Errorf("expected highest app-v3.0.0, got %q, %v", lookup.Tag, err)
+			}
+			if ignored := lookup.Unreachable; ignored != nil {
+				t.Errorf("expected no ignored tags, got %v", ignored)
+			}
+		})
 	})
 }
 
-func TestFindLastTagByPrefix_SimpleVTags(t *testing.T) {
+func TestFindLastTagByPrefix_VersionLine(t *testing.T) {
+	forEachBackend(t, func(t *testing.T) {
+		dir, cleanup := testRepo(t)
+		defer cleanup()
+
+		makeCommit(t, dir, "initial")
+		makeTag(t, dir, "app-v2.4.0")
+		makeCommit(t, dir, "fix: maintenance")
+		makeTag(t, dir, "app-v2.4.1")
+		makeCommit(t, dir, "feat!: next major")
+		makeTag(t, dir, "app-v3.0.0")
+
+		line := version.Line{Major: 2, Minor: -1}
+		SetVersionLine(&line)
+		defer SetVersionLine(nil)
+		withDir(dir, func() {
+			tag, _, err := FindLastTagByPrefix("app")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tag != "app-v2.4.1" {
+				t.Errorf("expected app-v2.4.1 on the 2.x line, got %q", tag)
+			}
+			if err := CheckNewTag("app", version.Version{Major: 2, Minor: 5}); err != nil {
+				t.Errorf("expected 2.5.0 to be allowed on the 2.x line, got %v", err)
+			}
+			if err := CheckNewTag("app", version.Version{Major: 2, Minor: 4}); err == nil {
+				t.Error("expected 2.4.0 to be refused")
+			}
+		})
+	})
+}
+
+func TestBackends_Shallow(t *testing.T) {
 	dir, cleanup := testRepo(t)
 	defer cleanup()
 
-	makeCommit(t, dir, "initial commit")
+	makeCommit(t, dir, "initial")
+	makeCommit(t, dir, "feat: second")
+	makeCommit(t, dir, "fix: third")
 
-	withDir(dir, func() {
-		// Create simple v* tags (no product prefix)
-		makeTag(t, dir, "v1.0.0")
-		makeCommit(t, dir, "another commit")
-		makeTag(t, dir, "v1.1.0")
-		makeCommit(t, dir, "yet another commit")
-		makeTag(t, dir, "v2.0.0")
-
-		// Also create a prefixed tag that should be ignored
-		makeTag(t, dir, "other-v3.0.0")
-
-		// Empty prefix should find simple v* tags
-		tag, v, err := FindLastTagByPrefix("")
-		if err != nil {
-			t.Fatalf("unexpected error: %v", err)
-		}
-		if tag != "v2.0.0" {
-			t.Errorf("expected v2.0.0, got %q", tag)
-		}
-		if v.String() != "2.0.0" {
-			t.Errorf("expected 2.0.0, got %v", v)
+	clone := filepath.Join(t.TempDir(), "clone")
+	if err := runGit("", "clone", "-q", "--depth", "2", "file://"+dir, clone); err != nil {
+		t.Fatalf("failed to clone: %v", err)
+	}
+
+	withDir(clone, func() {
+		for _, name := range []string{BackendExec, BackendGoGit} {
+			b, _ := NewBackend(name)
+			if shallow, err := b.IsShallow(); err != nil || !shallow {
+				t.Errorf("%s: IsShallow = %v, %v; want true", name, shallow, err)
+			}
+			commits, err := b.Log("", LogOptions{Files: true})
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", name, err)
+			}
+			if len(commits) != 2 {
+				t.Errorf("%s: expected 2 commits in shallow clone, got %d", name, len(commits))
+			}
 		}
 	})
 }
 
-func TestFindLastTagByPrefix_NoMatchingTags(t *testing.T) {
+func TestEnsureFullHistoryToTag_FetchPolicy(t *testing.T) {
 	dir, cleanup := testRepo(t)
 	defer cleanup()
 
-	makeCommit(t, dir, "initial commit")
+	makeCommit(t, dir, "initial")
+	makeTag(t, dir, "app-v1.0.0")
+	makeCommit(t, dir, "feat: second")
+	makeCommit(t, dir, "fix: third")
 
-	withDir(dir, func() {
-		// Create only prefixed tags
-		makeTag(t, dir, "product-v1.0.0")
+	clone := filepath.Join(t.TempDir(), "clone")
+	if err := runGit("", "clone", "-q", "--depth", "1", "file://"+dir, clone); err != nil {
+		t.Fatalf("failed to clone: %v", err)
+	}
+	if err := runGit(clone, "remote", "rename", "origin", "upstream"); err != nil {
+		t.Fatalf("failed to rename remote: %v", err)
+	}
 
-		// Empty prefix should not find prefixed tags
-		tag, v, err := FindLastTagByPrefix("")
-		if err != nil {
-			t.Fatalf("unexpected error: %v", err)
+	defer SetFetchPolicy("")
+	defer SetRemote("")
+	SetRemote("upstream")
+	withDir(clone, func() {
+		SetFetchPolicy(FetchNever)
+		before := len(Fetches())
+		if fetched, err := EnsureFullHistoryToTag("app-v1.0.0"); fetched || err != nil {
+			t.Errorf("never: EnsureFullHistoryToTag() = %v, %v; want no fetch", fetched, err)
+		}
+		if !IsShallowRepo() || len(Fetches()) != before {
+			t.Error("never: expected the clone to stay shallow without fetches")
+		}
+
+		SetFetchPolicy(FetchError)
+		_, err := EnsureFullHistoryToTag("app-v1.0.0")
+		var required *ErrFetchRequired
+		if !errors.As(err, &required) || required.Reason != "shallow repository" {
+			t.Errorf("error: expected ErrFetchRequired for the shallow clone, got %v", err)
 		}
-		if tag != "" {
-			t.Errorf("expected empty tag, got %q", tag)
+		if _, err := GetCommitsSinceWithFiles("app-v1.0.0"); !errors.As(err, &required) {
+			t.Errorf("error: expected GetCommitsSinceWithFiles to return ErrFetchRequired, got %v", err)
 		}
-		if v != version.Zero() {
-			t.Errorf("expected zero version, got %v", v)
+
+		SetFetchPolicy(FetchAuto)
+		if fetched, err := EnsureFullHistoryToTag("app-v1.0.0"); !fetched || err != nil {
+			t.Errorf("auto: EnsureFullHistoryToTag() = %v, %v; want a fetch", fetched, err)
+		}
+		if IsShallowRepo() {
+			t.Error("auto: expected the clone to be unshallowed")
+		}
+		fetches := Fetches()[before:]
+		if len(fetches) == 0 || fetches[0].String() != "git fetch upstream --unshallow" || fetches[0].Err != nil {
+			t.Errorf("auto: expected an unshallow fetch from upstream, got %+v", fetches)
 		}
 	})
 }
 
-func TestIsGitRepository(t *testing.T) {
-	// Test in a git repo
+func TestNewBackend(t *testing.T) {
+	for _, name := range []string{"", BackendExec, BackendGoGit} {
+		if _, err := NewBackend(name); err != nil {
+			t.Errorf("NewBackend(%q) unexpected error: %v", name, err)
+		}
+	}
+	if _, err := NewBackend("libgit2"); err == nil {
+		t.Error("expected error for unknown backend")
+	}
+}
+
+func TestGoGitBackend_ReachableCache(t *testing.T) {
 	dir, cleanup := testRepo(t)
 	defer cleanup()
 
-	withDir(dir, func() {
-		if !IsGitRepository() {
-			t.Error("expected IsGitRepository to return true")
-		}
-	})
-
-	// Test in a non-git directory
-	nonGitDir, err := os.MkdirTemp("", "non-git-*")
-	if err != nil {
+	makeCommit(t, dir, "feat: base")
+	makeTag(t, dir, "v1.0.0")
+	if err := runGit(dir, "checkout", "-q", "-b", "side"); err != nil {
+		t.Fatal(err)
+	}
+	makeCommit(t, dir, "feat: side")
+	makeTag(t, dir, "v2.0.0")
+	if err := runGit(dir, "checkout", "-q", "-"); err != nil {
 		t.Fatal(err)
 	}
-	defer os.RemoveAll(nonGitDir)
+	makeCommit(t, dir, "fix: main")
+
+	withDir(dir, func() {
+		resetReachableCache()
+		b := &GoGitBackend{}
+		for range 3 {
+			for tag, want := range map[string]bool{"v1.0.0": true, "v2.0.0": false} {
+				got, err := b.IsAncestor(tag, "HEAD")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != want {
+					t.Errorf("IsAncestor(%s, HEAD) = %v, want %v", tag, got, want)
+				}
+			}
+			tags, err := b.Tags("HEAD")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(tags, " ") != "v1.0.0" {
+				t.Errorf("expected only v1.0.0 to be merged, got %v", tags)
+			}
+		}
 
-	withDir(nonGitDir, func() {
-		if IsGitRepository() {
-			t.Error("expected IsGitRepository to return false")
+		// Every check above was answered from the one walk of HEAD
+		if len(reachableCache) != 1 {
+			t.Errorf("expected HEAD to be walked once, got %d cached walks", len(reachableCache))
 		}
 	})
 }
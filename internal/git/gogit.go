@@ -0,0 +1,502 @@
+package git
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// GoGitBackend implements Backend in-process using go-git, so no git binary is
+// needed. The repository containing the working directory is opened on first use and
+// reused until the working directory changes or a fetch updates it.
+type GoGitBackend struct {
+	mu   sync.Mutex
+	dir  string // Working directory repo was opened from
+	repo *gogit.Repository
+}
+
+// Name returns "go-git".
+func (*GoGitBackend) Name() string {
+	return BackendGoGit
+}
+
+// open returns the repository containing the working directory.
+func (b *GoGitBackend) open() (*gogit.Repository, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open repository: %w", err)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.repo != nil && b.dir == dir {
+		return b.repo, nil
+	}
+	repo, err := gogit.PlainOpenWithOptions(dir, &gogit.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open repository: %w", err)
+	}
+	b.dir, b.repo = dir, repo
+	return repo, nil
+}
+
+// reset forgets the opened repository, so the next call reopens it.
+func (b *GoGitBackend) reset() {
+	b.mu.Lock()
+	b.repo = nil
+	b.mu.Unlock()
+}
+
+// IsRepository checks if the current directory is inside a git repository.
+func (b *GoGitBackend) IsRepository() bool {
+	_, err := b.open()
+	return err == nil
+}
+
+// ResolveCommit resolves a ref to its underlying commit hash, peeling annotated tags.
+func (b *GoGitBackend) ResolveCommit(ref string) (string, error) {
+	repo, err := b.open()
+	if err != nil {
+		return "", err
+	}
+	c, err := resolveCommit(repo, ref)
+	if err != nil {
+		return "", err
+	}
+	return c.Hash.String(), nil
+}
+
+// resolveCommit resolves a tag, branch, "HEAD" or hash to a commit.
+func resolveCommit(repo *gogit.Repository, ref string) (*object.Commit, error) {
+	// Tags are looked up first so annotated tags can be peeled to their commit
+	if tagRef, err := repo.Tag(ref); err == nil {
+		if tagObj, err := repo.TagObject(tagRef.Hash()); err == nil {
+			c, err := tagObj.Commit()
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve %s to commit: %w", ref, err)
+			}
+			return c, nil
+		}
+		return commitObject(repo, ref, tagRef.Hash())
+	}
+
+	hash, err := repo.ResolveRevision(plumbing.Revision(ref))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s to commit: %w", ref, err)
+	}
+	return commitObject(repo, ref, *hash)
+}
+
+// commitObject loads a commit, reporting errors against the ref it was resolved from.
+func commitObject(repo *gogit.Repository, ref string, hash plumbing.Hash) (*object.Commit, error) {
+	c, err := repo.CommitObject(hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s to commit: %w", ref, err)
+	}
+	return c, nil
+}
+
+// RefName returns the full name of r, trying the prefixes git rev-parse does for
+// abbreviated names, or the branch HEAD points to.
+func (b *GoGitBackend) RefName(r string) (string, error) {
+	repo, err := b.open()
+	if err != nil {
+		return "", err
+	}
+	if r == "HEAD" {
+		head, err := repo.Reference(plumbing.HEAD, false)
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve HEAD: %w", err)
+		}
+		if head.Type() != plumbing.SymbolicReference {
+			return "", nil
+		}
+		return head.Target().String(), nil
+	}
+
+	for _, prefix := range []string{"", "refs/", "refs/tags/", "refs/heads/", "refs/remotes/"} {
+		name := plumbing.ReferenceName(prefix + r)
+		if _, err := repo.Reference(name, false); err == nil {
+			return name.String(), nil
+		}
+	}
+	// Anything else that resolves, like a hash, names no ref
+	if _, err := resolveCommit(repo, r); err != nil {
+		return "", err
+	}
+	return "", nil
+}
+
+// Tags lists tag names, keeping only those whose commit is reachable from merged if set.
+func (b *GoGitBackend) Tags(merged string) ([]string, error) {
+	repo, err := b.open()
+	if err != nil {
+		return nil, err
+	}
+	iter, err := repo.Tags()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+
+	var reachable map[plumbing.Hash]bool
+	if merged != "" {
+		start, err := resolveCommit(repo, merged)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tags: %w", err)
+		}
+		if reachable, err = reachableFrom(repo, start); err != nil {
+			return nil, fmt.Errorf("failed to list tags: %w", err)
+		}
+	}
+
+	var tags []string
+	err = iter.ForEach(func(ref *plumbing.Reference) error {
+		name := ref.Name().Short()
+		if reachable != nil {
+			// Tags that do not point at a commit are never merged
+			c, err := resolveCommit(repo, name)
+			if err != nil || !reachable[c.Hash] {
+				return nil
+			}
+		}
+		tags = append(tags, name)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+	sort.Strings(tags)
+	return tags, nil
+}
+
+// Log walks the history from opts.Ref, newest first by committer date like git log.
+func (b *GoGitBackend) Log(since string, opts LogOptions) ([]CommitInfo, error) {
+	repo, err := b.open()
+	if err != nil {
+		return nil, err
+	}
+	commits, err := commitsSince(repo, since, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]CommitInfo, 0, len(commits))
+	for _, c := range commits {
+		subject, body := splitMessage(c.Message)
+		ci := CommitInfo{Hash: c.Hash.String(), Subject: subject, Body: body}
+		for _, h := range c.ParentHashes {
+			ci.Parents = append(ci.Parents, h.String())
+		}
+		if opts.Files {
+			ci.Files, err = changedFiles(repo, c, opts.FirstParent)
+			if err != nil {
+				return nil, err
+			}
+		}
+		result = append(result, ci)
+	}
+	return result, nil
+}
+
+// CountCommits counts the commits between since and opts.Ref.
+func (b *GoGitBackend) CountCommits(since string, opts LogOptions) (int, error) {
+	repo, err := b.open()
+	if err != nil {
+		return 0, err
+	}
+	commits, err := commitsSince(repo, since, opts)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count commits: %w", err)
+	}
+	return len(commits), nil
+}
+
+// IsAncestor reports whether ancestor is reachable from descendant.
+// Refs that cannot be resolved are reported as not ancestors, like the exec backend.
+func (b *GoGitBackend) IsAncestor(ancestor, descendant string) (bool, error) {
+	repo, err := b.open()
+	if err != nil {
+		return false, err
+	}
+	a, err := resolveCommit(repo, ancestor)
+	if err != nil {
+		return false, nil
+	}
+	d, err := resolveCommit(repo, descendant)
+	if err != nil {
+		return false, nil
+	}
+
+	reachable, err := reachableFrom(repo, d)
+	if err != nil {
+		return false, err
+	}
+	return reachable[a.Hash], nil
+}
+
+// IsShallow checks if the repository is a shallow clone.
+func (b *GoGitBackend) IsShallow() (bool, error) {
+	repo, err := b.open()
+	if err != nil {
+		return false, err
+	}
+	shallow, err := repo.Storer.Shallow()
+	if err != nil {
+		return false, fmt.Errorf("failed to check for shallow repository: %w", err)
+	}
+	return len(shallow) > 0, nil
+}
+
+// MergeBase folds pairwise merge bases over refs, giving a common ancestor of all of them.
+func (b *GoGitBackend) MergeBase(refs ...string) (string, error) {
+	repo, err := b.open()
+	if err != nil {
+		return "", err
+	}
+	if len(refs) == 0 {
+		return "", fmt.Errorf("failed to find merge base: no refs given")
+	}
+
+	base, err := resolveCommit(repo, refs[0])
+	if err != nil {
+		return "", err
+	}
+	for _, ref := range refs[1:] {
+		c, err := resolveCommit(repo, ref)
+		if err != nil {
+			return "", err
+		}
+		bases, err := base.MergeBase(c)
+		if err != nil {
+			return "", fmt.Errorf("failed to find merge base of %s: %w", strings.Join(refs, ", "), err)
+		}
+		if len(bases) == 0 {
+			return "", fmt.Errorf("failed to find merge base of %s: no common ancestor", strings.Join(refs, ", "))
+		}
+		base = bases[0]
+	}
+	return base.Hash.String(), nil
+}
+
+// commitsSince returns the commits reachable from opts.Ref but not from since, following
+// only first parents from opts.Ref if opts.FirstParent is set.
+func commitsSince(repo *gogit.Repository, since string, opts LogOptions) ([]*object.Commit, error) {
+	head, err := resolveCommit(repo, opts.head())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get git log: %w", err)
+	}
+
+	var exclude map[plumbing.Hash]bool
+	if since != "" {
+		start, err := resolveCommit(repo, since)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get git log: %w", err)
+		}
+		if exclude, err = reachableFrom(repo, start); err != nil {
+			return nil, fmt.Errorf("failed to get git log: %w", err)
+		}
+	}
+
+	commits, err := walkCommits(repo, head, exclude, opts.FirstParent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get git log: %w", err)
+	}
+	return commits, nil
+}
+
+// reachableCache holds the commits reachable from each commit walked so far, keyed by
+// its hash, so tag and ancestry checks repeated for every target walk the graph once
+// per ref. A hash fixes its whole ancestry, so entries only go stale when a fetch
+// fills in a shallow history, which resets the cache.
+var (
+	reachableMu    sync.Mutex
+	reachableCache = map[plumbing.Hash]map[plumbing.Hash]bool{}
+)
+
+// resetReachableCache forgets the cached reachable sets.
+func resetReachableCache() {
+	reachableMu.Lock()
+	reachableCache = map[plumbing.Hash]map[plumbing.Hash]bool{}
+	reachableMu.Unlock()
+}
+
+// reachableFrom returns the set of commits reachable from start, including start.
+// The returned map is shared and must not be modified.
+func reachableFrom(repo *gogit.Repository, start *object.Commit) (map[plumbing.Hash]bool, error) {
+	reachableMu.Lock()
+	reachable, ok := reachableCache[start.Hash]
+	reachableMu.Unlock()
+	if ok {
+		return reachable, nil
+	}
+
+	commits, err := walkCommits(repo, start, nil, false)
+	if err != nil {
+		return nil, err
+	}
+	reachable = make(map[plumbing.Hash]bool, len(commits))
+	for _, c := range commits {
+		reachable[c.Hash] = true
+	}
+
+	reachableMu.Lock()
+	reachableCache[start.Hash] = reachable
+	reachableMu.Unlock()
+	return reachable, nil
+}
+
+// walkCommits returns start and its ancestors, newest committer date first, not
+// descending into commits in exclude, nor past the first parent if firstParent is set.
+// Parents missing at a shallow boundary are skipped.
+func walkCommits(repo *gogit.Repository, start *object.Commit, exclude map[plumbing.Hash]bool, firstParent bool) ([]*object.Commit, error) {
+	if exclude[start.Hash] {
+		return nil, nil
+	}
+
+	seen := map[plumbing.Hash]bool{start.Hash: true}
+	queue := &commitQueue{}
+	heap.Push(queue, start)
+	var commits []*object.Commit
+	for queue.Len() > 0 {
+		c := heap.Pop(queue).(*object.Commit)
+		commits = append(commits, c)
+
+		parents := c.ParentHashes
+		if firstParent && len(parents) > 1 {
+			parents = parents[:1]
+		}
+		for _, h := range parents {
+			if seen[h] || exclude[h] {
+				continue
+			}
+			seen[h] = true
+			parent, err := repo.CommitObject(h)
+			if errors.Is(err, plumbing.ErrObjectNotFound) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			heap.Push(queue, parent)
+		}
+	}
+	return commits, nil
+}
+
+// commitQueue is a heap of commits ordered by committer date, newest first.
+// Commits with equal dates come out in the order they were pushed, as in git.
+type commitQueue struct {
+	items []queuedCommit
+	next  int
+}
+
+type queuedCommit struct {
+	commit *object.Commit
+	seq    int
+}
+
+func (q commitQueue) Len() int { return len(q.items) }
+func (q commitQueue) Less(i, j int) bool {
+	a, b := q.items[i], q.items[j]
+	if !a.commit.Committer.When.Equal(b.commit.Committer.When) {
+		return a.commit.Committer.When.After(b.commit.Committer.When)
+	}
+	return a.seq < b.seq
+}
+func (q commitQueue) Swap(i, j int) { q.items[i], q.items[j] = q.items[j], q.items[i] }
+func (q *commitQueue) Push(x any) {
+	q.items = append(q.items, queuedCommit{commit: x.(*object.Commit), seq: q.next})
+	q.next++
+}
+func (q *commitQueue) Pop() any {
+	last := q.items[len(q.items)-1]
+	q.items = q.items[:len(q.items)-1]
+	return last.commit
+}
+
+// splitMessage splits a commit message into subject and body as git log's %s and
+// %b do: the subject is the first paragraph with its lines joined by spaces.
+func splitMessage(message string) (string, string) {
+	message = strings.TrimLeft(message, "\n")
+	subject, body, _ := strings.Cut(message, "\n\n")
+	subject = strings.Join(strings.Fields(subject), " ")
+	return subject, strings.TrimSpace(body)
+}
+
+// ChangedFiles returns the files a commit changed relative to its parent.
+func (b *GoGitBackend) ChangedFiles(hash string) ([]string, error) {
+	repo, err := b.open()
+	if err != nil {
+		return nil, err
+	}
+	c, err := resolveCommit(repo, hash)
+	if err != nil {
+		return nil, err
+	}
+	return changedFiles(repo, c, false)
+}
+
+// changedFiles returns the files a commit changed relative to its parent, sorted by
+// path. Merge commits have no files unless firstParent is set, in which case they are
+// diffed against their first parent; root commits (and commits at a shallow boundary)
+// list every file in their tree. Renamed files are listed under their new name.
+func changedFiles(repo *gogit.Repository, c *object.Commit, firstParent bool) ([]string, error) {
+	if c.NumParents() > 1 && !firstParent {
+		return nil, nil
+	}
+
+	tree, err := c.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get files for %s: %w", c.Hash, err)
+	}
+
+	var parentTree *object.Tree
+	if c.NumParents() >= 1 {
+		parent, err := repo.CommitObject(c.ParentHashes[0])
+		if err != nil && !errors.Is(err, plumbing.ErrObjectNotFound) {
+			return nil, fmt.Errorf("failed to get files for %s: %w", c.Hash, err)
+		}
+		if parent != nil {
+			parentTree, err = parent.Tree()
+			if err != nil {
+				return nil, fmt.Errorf("failed to get files for %s: %w", c.Hash, err)
+			}
+		}
+	}
+
+	var files []string
+	if parentTree == nil {
+		err = tree.Files().ForEach(func(f *object.File) error {
+			files = append(files, f.Name)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get files for %s: %w", c.Hash, err)
+		}
+		sort.Strings(files)
+		return files, nil
+	}
+
+	changes, err := object.DiffTreeWithOptions(context.Background(), parentTree, tree, &object.DiffTreeOptions{DetectRenames: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get files for %s: %w", c.Hash, err)
+	}
+	for _, change := range changes {
+		name := change.To.Name
+		if name == "" {
+			name = change.From.Name
+		}
+		files = append(files, name)
+	}
+	sort.Strings(files)
+	return files, nil
+}
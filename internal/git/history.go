@@ -0,0 +1,210 @@
+package git
+
+// History holds the commits since a set of tags, loaded with a single walk from HEAD
+// (or the ref set with SetRef) back to a common ancestor of the tags. It lets many
+// product-variants be evaluated without running git log once per tag.
+type History struct {
+	base        string // Common ancestor of all tags; empty if history was loaded to the root
+	commits     []CommitInfo
+	index       map[string]int // Commit hash to position in commits
+	firstParent bool           // Whether commits were walked by first parent only
+}
+
+// LoadHistory loads the commits since the oldest of tags, with their changed files.
+// An empty tag means "no tag yet" and loads the full history. Missing history is
+// fetched and verified as by GetCommitsSinceWithFiles.
+func LoadHistory(tags []string) (*History, error) {
+	h := &History{index: map[string]int{}}
+	if !hasCommits() {
+		return h, nil
+	}
+
+	var unique []string
+	seen := map[string]bool{}
+	full := len(tags) == 0
+	for _, tag := range tags {
+		if tag == "" {
+			full = true
+			continue
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			unique = append(unique, tag)
+		}
+	}
+
+	// Shallow clones are completed up front, as later checks need the full graph
+	if IsShallowRepo() {
+		for _, tag := range unique {
+			if err := ensureTagHistory(tag); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := h.load(unique, full); err != nil {
+		return nil, err
+	}
+
+	// A tag outside the loaded range is not reachable from the ref; try to fetch it
+	refetched := false
+	for _, tag := range unique {
+		if h.contains(tag) {
+			continue
+		}
+		if err := ensureTagHistory(tag); err != nil {
+			return nil, err
+		}
+		refetched = true
+	}
+	if refetched {
+		if err := h.load(unique, full); err != nil {
+			return nil, err
+		}
+	}
+
+	return h, nil
+}
+
+// load walks history from the ref back to the merge base of tags.
+func (h *History) load(tags []string, full bool) error {
+	h.base = ""
+	if !full && len(tags) > 0 {
+		// Tags without a common ancestor (unrelated histories) load the full history
+		if base, err := backend.MergeBase(tags...); err == nil {
+			h.base = base
+		}
+	}
+
+	opts := logOptions(true)
+	commits, err := backend.Log(h.base, opts)
+	if err != nil {
+		return err
+	}
+	if err := verifyCommitsSince(h.base, commits); err != nil {
+		return err
+	}
+
+	h.commits = commits
+	h.firstParent = opts.FirstParent
+	h.index = make(map[string]int, len(commits))
+	for i, c := range commits {
+		h.index[c.Hash] = i
+	}
+	return nil
+}
+
+// contains reports whether tag points at the base or a loaded commit.
+func (h *History) contains(tag string) bool {
+	hash, err := backend.ResolveCommit(tag)
+	if err != nil {
+		return false
+	}
+	_, ok := h.index[hash]
+	return ok || hash == h.base
+}
+
+// Len returns the number of loaded commits.
+func (h *History) Len() int {
+	return len(h.commits)
+}
+
+// CommitsSince returns the loaded commits reachable from the ref but not from tag, newest
+// first, like GetCommitsSinceWithFiles. Walked by first parent, they are the ref's first
+// parents down to the tag's first-parent ancestor on that line. Tags outside the loaded
+// range fall back to GetCommitsSinceWithFiles.
+func (h *History) CommitsSince(tag string) ([]CommitInfo, error) {
+	if tag == "" {
+		if h.base != "" {
+			return GetCommitsSinceWithFiles(tag)
+		}
+		return h.commits, nil
+	}
+
+	hash, err := backend.ResolveCommit(tag)
+	if err != nil {
+		return GetCommitsSinceWithFiles(tag)
+	}
+	if hash == h.base {
+		return h.commits, nil
+	}
+	if h.firstParent {
+		return h.firstParentCommitsSince(tag, hash)
+	}
+	if _, ok := h.index[hash]; !ok {
+		return GetCommitsSinceWithFiles(tag)
+	}
+
+	// Mark the tag's commit and its ancestors within the loaded range
+	excluded := map[string]bool{hash: true}
+	queue := []string{hash}
+	for len(queue) > 0 {
+		i, ok := h.index[queue[0]]
+		queue = queue[1:]
+		if !ok {
+			continue
+		}
+		for _, p := range h.commits[i].Parents {
+			if !excluded[p] {
+				excluded[p] = true
+				queue = append(queue, p)
+			}
+		}
+	}
+
+	var commits []CommitInfo
+	for _, c := range h.commits {
+		if !excluded[c.Hash] {
+			commits = append(commits, c)
+		}
+	}
+	return commits, nil
+}
+
+// firstParentCommitsSince follows first parents from the ref down to the tag's
+// first-parent ancestor on that line: the tag's commit if it is on it, or where the
+// branch the tag sits on forked from it.
+func (h *History) firstParentCommitsSince(tag, hash string) ([]CommitInfo, error) {
+	stop, ok := hash, false
+	if _, ok = h.index[hash]; !ok {
+		// Tagged on a merged side branch; walk its own first parents to the mainline
+		if merged, err := backend.IsAncestor(hash, ref); err != nil || !merged {
+			return GetCommitsSinceWithFiles(tag)
+		}
+		side, err := backend.Log(h.base, LogOptions{FirstParent: true, Ref: tag})
+		if err != nil || len(side) == 0 {
+			return GetCommitsSinceWithFiles(tag)
+		}
+		for _, c := range side {
+			if _, ok = h.index[c.Hash]; ok {
+				stop = c.Hash
+				break
+			}
+		}
+		if last := side[len(side)-1]; !ok && len(last.Parents) > 0 {
+			stop = last.Parents[0]
+			_, ok = h.index[stop]
+			ok = ok || stop == h.base
+		}
+		if !ok {
+			return GetCommitsSinceWithFiles(tag)
+		}
+	}
+
+	var commits []CommitInfo
+	if len(h.commits) == 0 {
+		return commits, nil
+	}
+	for c := h.commits[0]; c.Hash != stop; {
+		commits = append(commits, c)
+		if len(c.Parents) == 0 {
+			break
+		}
+		i, ok := h.index[c.Parents[0]]
+		if !ok {
+			break
+		}
+		c = h.commits[i]
+	}
+	return commits, nil
+}
@@ -0,0 +1,159 @@
+package git
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestLoadHistory(t *testing.T) {
+	forEachBackend(t, func(t *testing.T) {
+		dir, cleanup := testRepo(t)
+		defer cleanup()
+
+		makeCommit(t, dir, "initial")
+		makeTag(t, dir, "web-v1.0.0")
+		makeCommit(t, dir, "feat: first")
+		if err := runGit(dir, "tag", "-a", "api-v1.0.0", "-m", "Release api 1.0.0"); err != nil {
+			t.Fatal(err)
+		}
+
+		// A tag on a merged side branch is not an ancestor of the other tags
+		if err := runGit(dir, "checkout", "-q", "-b", "side"); err != nil {
+			t.Fatal(err)
+		}
+		makeCommit(t, dir, "fix: on side")
+		makeTag(t, dir, "side-v1.0.0")
+		if err := runGit(dir, "checkout", "-q", "-"); err != nil {
+			t.Fatal(err)
+		}
+		makeCommit(t, dir, "feat: second")
+		if err := runGit(dir, "merge", "-q", "--no-ff", "-X", "theirs", "side", "-m", "Merge branch 'side'"); err != nil {
+			t.Fatal(err)
+		}
+		makeCommit(t, dir, "fix: third")
+		makeTag(t, dir, "cli-v1.0.0")
+
+		withDir(dir, func() {
+			tags := []string{"web-v1.0.0", "api-v1.0.0", "side-v1.0.0", "cli-v1.0.0", ""}
+			h, err := LoadHistory(tags)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if h.Len() != 6 {
+				t.Errorf("expected 6 loaded commits, got %d", h.Len())
+			}
+
+			for _, tag := range tags {
+				got, err := h.CommitsSince(tag)
+				if err != nil {
+					t.Fatalf("CommitsSince(%q) unexpected error: %v", tag, err)
+				}
+				want, err := GetCommitsSinceWithFiles(tag)
+				if err != nil {
+					t.Fatalf("GetCommitsSinceWithFiles(%q) unexpected error: %v", tag, err)
+				}
+				if len(got) != len(want) {
+					t.Errorf("CommitsSince(%q) returned %d commits, want %d", tag, len(got), len(want))
+					continue
+				}
+				for i := range got {
+					if got[i].Hash != want[i].Hash || strings.Join(got[i].Files, ",") != strings.Join(want[i].Files, ",") {
+						t.Errorf("CommitsSince(%q) commit %d = %+v, want %+v", tag, i, got[i], want[i])
+					}
+				}
+			}
+		})
+	})
+}
+
+func TestLoadHistory_NoCommits(t *testing.T) {
+	dir, cleanup := testRepo(t)
+	defer cleanup()
+
+	withDir(dir, func() {
+		h, err := LoadHistory([]string{""})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		commits, err := h.CommitsSince("")
+		if err != nil || len(commits) != 0 {
+			t.Errorf("expected no commits, got %d, %v", len(commits), err)
+		}
+	})
+}
+
+// benchRepo generates a repository with one directory and tag per target and
+// commitsPerTarget commits round-robin across them, tagging each target halfway.
+func benchRepo(b *testing.B, targets, commitsPerTarget int) (string, []string) {
+	b.Helper()
+
+	dir := b.TempDir()
+	if err := runGit(dir, "init", "-q"); err != nil {
+		b.Fatalf("failed to init git repo: %v", err)
+	}
+
+	// fast-import builds the history far quicker than one git commit per change
+	var stream strings.Builder
+	total := targets * commitsPerTarget
+	var tags []string
+	for i := 1; i <= total; i++ {
+		target := i % targets
+		content := fmt.Sprintf("change %d\n", i)
+		fmt.Fprintf(&stream, "commit refs/heads/master\nmark :%d\n", i)
+		fmt.Fprintf(&stream, "committer Test User <test@test.com> %d +0000\n", 1700000000+i)
+		msg := fmt.Sprintf("feat(app%d): change %d\n", target, i)
+		fmt.Fprintf(&stream, "data %d\n%s", len(msg), msg)
+		if i > 1 {
+			fmt.Fprintf(&stream, "from :%d\n", i-1)
+		}
+		fmt.Fprintf(&stream, "M 644 inline apps/app%d/file.txt\ndata %d\n%s\n", target, len(content), content)
+		if i > total/2 && len(tags) < targets {
+			tag := fmt.Sprintf("app%d-v1.0.0", len(tags))
+			fmt.Fprintf(&stream, "reset refs/tags/%s\nfrom :%d\n\n", tag, i)
+			tags = append(tags, tag)
+		}
+	}
+
+	cmd := exec.Command("git", "fast-import", "--quiet")
+	cmd.Dir = dir
+	cmd.Stdin = strings.NewReader(stream.String())
+	if out, err := cmd.CombinedOutput(); err != nil {
+		b.Fatalf("failed to import history: %v\n%s", err, out)
+	}
+	if err := runGit(dir, "checkout", "-q", "master"); err != nil {
+		b.Fatalf("failed to check out: %v", err)
+	}
+	return dir, tags
+}
+
+// BenchmarkHistory compares one git log per target with a single shared walk.
+func BenchmarkHistory(b *testing.B) {
+	dir, tags := benchRepo(b, 40, 50)
+
+	withDir(dir, func() {
+		b.Run("PerTarget", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for _, tag := range tags {
+					if _, err := GetCommitsSinceWithFiles(tag); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+		b.Run("Shared", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				h, err := LoadHistory(tags)
+				if err != nil {
+					b.Fatal(err)
+				}
+				for _, tag := range tags {
+					if _, err := h.CommitsSince(tag); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	})
+}
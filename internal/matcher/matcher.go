@@ -8,50 +8,177 @@ import (
 
 // Matcher handles file-to-product-variant matching logic.
 type Matcher struct {
-	config *config.Config
-	globs  map[string][]glob.Glob // Compiled globs per product (multiple per product)
+	config          *config.Config
+	globs           map[string][]glob.Glob // Compiled globs per product (multiple per product)
+	patterns        map[string][]string    // Source patterns, parallel to globs
+	excludes        map[string][]glob.Glob // Compiled exclusion globs per product
+	excludePatterns map[string][]string    // Source exclusion patterns, parallel to excludes
+	ignores         []glob.Glob            // Compiled global ignore globs
+	ignorePatterns  []string               // Source ignore patterns, parallel to ignores
+
+	variantGlobs    map[string]map[string][]glob.Glob // Compiled globs per product, then variant
+	variantPatterns map[string]map[string][]string    // Source variant patterns, parallel to variantGlobs
+}
+
+// FileMatch records that a file matched one of a product's glob patterns. For
+// exclusions reported by ExcludedFiles, Glob is the exclusion pattern; for files
+// reported by IgnoredFiles, Glob is the ignore pattern and Product is empty.
+type FileMatch struct {
+	File    string `json:"file"`
+	Product string `json:"product"`
+	Variant string `json:"variant,omitempty"` // Set if Glob belongs to a single variant
+	Glob    string `json:"glob"`
 }
 
+// Scope handling outcomes reported by ExplainScope.
+const (
+	ScopeIgnored  = "ignored"  // Product has no variants, scope does not matter
+	ScopeUnscoped = "unscoped" // No scope, all variants
+	ScopeMatched  = "matched"  // Scopes name variants, only those variants
+	ScopeUnknown  = "unknown"  // Scope names no variant, treated as unscoped
+	ScopePath     = "path"     // No variant scope, variants derived from the changed files
+	ScopeProduct  = "product"  // Scope names the product itself, all variants
+	ScopeDropped  = "dropped"  // Unknown scope, commit ignored for the product (scope_mode ignore)
+	ScopeRejected = "rejected" // Unknown scope, not allowed for the product (scope_mode strict)
+)
+
 // NewMatcher creates a new Matcher with the given config.
 // Globs are compiled once for efficiency.
 func NewMatcher(cfg *config.Config) (*Matcher, error) {
 	m := &Matcher{
-		config: cfg,
-		globs:  make(map[string][]glob.Glob),
+		config:          cfg,
+		globs:           make(map[string][]glob.Glob),
+		patterns:        make(map[string][]string),
+		excludes:        make(map[string][]glob.Glob),
+		excludePatterns: make(map[string][]string),
+		variantGlobs:    make(map[string]map[string][]glob.Glob),
+		variantPatterns: make(map[string]map[string][]string),
 	}
 
 	// Compile all glob patterns for each product
 	for productName := range cfg.Products {
-		patterns, _ := cfg.GetGlobs(productName)
-		if len(patterns) == 0 {
-			patterns = []string{"**"} // Default: match all files
-		}
-		var compiledGlobs []glob.Glob
-		for _, pattern := range patterns {
-			g, err := glob.Compile(pattern, '/')
+		variantPatterns, _ := cfg.GetVariantGlobs(productName)
+		m.variantGlobs[productName] = make(map[string][]glob.Glob)
+		for variant, patterns := range variantPatterns {
+			compiled, err := compileGlobs(patterns)
 			if err != nil {
 				return nil, err
 			}
-			compiledGlobs = append(compiledGlobs, g)
+			m.variantGlobs[productName][variant] = compiled
+		}
+		m.variantPatterns[productName] = variantPatterns
+
+		patterns, _ := cfg.GetGlobs(productName)
+		if len(patterns) == 0 && len(variantPatterns) == 0 {
+			patterns = []string{"**"} // Default: match all files
+		}
+		compiledGlobs, err := compileGlobs(patterns)
+		if err != nil {
+			return nil, err
 		}
 		m.globs[productName] = compiledGlobs
+		m.patterns[productName] = patterns
+
+		excludePatterns, _ := cfg.GetExcludeGlobs(productName)
+		compiledExcludes, err := compileGlobs(excludePatterns)
+		if err != nil {
+			return nil, err
+		}
+		m.excludes[productName] = compiledExcludes
+		m.excludePatterns[productName] = excludePatterns
 	}
 
+	ignores, err := compileGlobs(cfg.Ignore)
+	if err != nil {
+		return nil, err
+	}
+	m.ignores = ignores
+	m.ignorePatterns = cfg.Ignore
+
 	return m, nil
 }
 
+// compileGlobs compiles path glob patterns.
+func compileGlobs(patterns []string) ([]glob.Glob, error) {
+	var compiled []glob.Glob
+	for _, pattern := range patterns {
+		g, err := glob.Compile(pattern, '/')
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, g)
+	}
+	return compiled, nil
+}
+
+// includedBy returns the index of the first of a product's globs matching file, or -1.
+func (m *Matcher) includedBy(product, file string) int {
+	for i, g := range m.globs[product] {
+		if g.Match(file) {
+			return i
+		}
+	}
+	return -1
+}
+
+// excludedBy returns the index of the first of a product's exclusions matching file, or -1.
+func (m *Matcher) excludedBy(product, file string) int {
+	for i, g := range m.excludes[product] {
+		if g.Match(file) {
+			return i
+		}
+	}
+	return -1
+}
+
+// ignoredBy returns the index of the first global ignore glob matching file, or -1.
+func (m *Matcher) ignoredBy(file string) int {
+	for i, g := range m.ignores {
+		if g.Match(file) {
+			return i
+		}
+	}
+	return -1
+}
+
+// variantsOf returns the variants of a product, in config order, whose own globs match file.
+func (m *Matcher) variantsOf(product, file string) []string {
+	var variants []string
+	for _, variant := range m.config.Products[product].Variants {
+		for _, g := range m.variantGlobs[product][variant] {
+			if g.Match(file) {
+				variants = append(variants, variant)
+				break
+			}
+		}
+	}
+	return variants
+}
+
+// owns reports whether file matches one of a product's globs, shared or per-variant.
+func (m *Matcher) owns(product, file string) bool {
+	return m.includedBy(product, file) >= 0 || len(m.variantsOf(product, file)) > 0
+}
+
+// matchFile reports whether file counts for a product: it must not be ignored, and
+// must match one of the product's globs and none of its exclusions.
+func (m *Matcher) matchFile(product, file string) bool {
+	return m.ignoredBy(file) < 0 && m.owns(product, file) && m.excludedBy(product, file) < 0
+}
+
 // MatchFiles returns which products are affected by a set of files.
-// A product is affected if any file matches any of its glob patterns (union logic).
+// Globally ignored files are dropped first; a product is then affected if any
+// remaining file matches any of its glob patterns (union logic) and none of its exclusions.
 func (m *Matcher) MatchFiles(files []string) []string {
 	matchedProducts := make(map[string]bool)
 
 	for _, file := range files {
-		for productName, globs := range m.globs {
-			for _, g := range globs {
-				if g.Match(file) {
-					matchedProducts[productName] = true
-					break // One match is enough for this product
-				}
+		if m.ignoredBy(file) >= 0 {
+			continue
+		}
+		for productName := range m.globs {
+			if m.matchFile(productName, file) {
+				matchedProducts[productName] = true
 			}
 		}
 	}
@@ -63,11 +190,82 @@ func (m *Matcher) MatchFiles(files []string) []string {
 	return result
 }
 
+// MatchingFiles returns the files that match any of a product's glob patterns
+// and none of its exclusions.
+func (m *Matcher) MatchingFiles(product string, files []string) []string {
+	var result []string
+	for _, file := range files {
+		if m.matchFile(product, file) {
+			result = append(result, file)
+		}
+	}
+	return result
+}
+
+// ExcludedFiles returns the files that match one of a product's glob patterns but
+// are suppressed by an exclusion, with the first exclusion that matched.
+func (m *Matcher) ExcludedFiles(product string, files []string) []FileMatch {
+	var result []FileMatch
+	for _, file := range files {
+		if m.ignoredBy(file) >= 0 || !m.owns(product, file) {
+			continue
+		}
+		if i := m.excludedBy(product, file); i >= 0 {
+			result = append(result, FileMatch{File: file, Product: product, Glob: m.excludePatterns[product][i]})
+		}
+	}
+	return result
+}
+
+// IgnoredFiles returns the files dropped by the global ignore list, with the first
+// ignore glob that matched.
+func (m *Matcher) IgnoredFiles(files []string) []FileMatch {
+	var result []FileMatch
+	for _, file := range files {
+		if i := m.ignoredBy(file); i >= 0 {
+			result = append(result, FileMatch{File: file, Glob: m.ignorePatterns[i]})
+		}
+	}
+	return result
+}
+
+// ExplainFiles returns, for each file, the first glob of each product that matched it.
+// Ignored files, and files suppressed by a product's exclusions, are left out.
+// Results are ordered by file, then product name.
+func (m *Matcher) ExplainFiles(files []string) []FileMatch {
+	products := m.config.ProductNames()
+
+	var result []FileMatch
+	for _, file := range files {
+		if m.ignoredBy(file) >= 0 {
+			continue
+		}
+		for _, product := range products {
+			if m.excludedBy(product, file) >= 0 {
+				continue
+			}
+			if i := m.includedBy(product, file); i >= 0 {
+				result = append(result, FileMatch{File: file, Product: product, Glob: m.patterns[product][i]})
+				continue
+			}
+			for _, variant := range m.variantsOf(product, file) {
+				for i, g := range m.variantGlobs[product][variant] {
+					if g.Match(file) {
+						result = append(result, FileMatch{File: file, Product: product, Variant: variant, Glob: m.variantPatterns[product][variant][i]})
+						break
+					}
+				}
+			}
+		}
+	}
+	return result
+}
+
 // MatchCommit determines which product-variants are affected by a single commit.
 // Logic:
 // 1. Check which products the commit's files match (via glob)
-// 2. If commit has no scope -> all variants of matched products
-// 3. If commit has scope -> only matching variant of matched products
+// 2. If commit has scopes -> only the variants they name in matched products
+// 3. Otherwise -> variants whose own globs match the files (see SelectVariants)
 func (m *Matcher) MatchCommit(c commit.Commit, files []string) []config.ProductVariant {
 	// Find products affected by files
 	matchedProducts := m.MatchFiles(files)
@@ -75,53 +273,148 @@ func (m *Matcher) MatchCommit(c commit.Commit, files []string) []config.ProductV
 		return nil
 	}
 
-	// Filter variants based on commit scope
-	return m.FilterVariantsByScope(matchedProducts, c.Scope)
+	// Filter variants based on commit scope and files
+	var result []config.ProductVariant
+	for _, product := range matchedProducts {
+		variants, _ := m.SelectVariants(product, c.ScopeList(), files)
+		result = append(result, variants...)
+	}
+	return result
 }
 
-// FilterVariantsByScope filters variants based on commit scope.
+// SelectVariants applies the ExplainScope rules to a product, then narrows commits
+// that do not name a variant in their scopes to the variants whose own globs match
+// the changed files (ScopePath). Commits touching any file matched by the product's
+// shared globs keep all variants.
+func (m *Matcher) SelectVariants(product string, scopes []string, files []string) ([]config.ProductVariant, string) {
+	variants, handling := m.ExplainScope(product, scopes...)
+	if handling != ScopeUnscoped && handling != ScopeUnknown && handling != ScopeProduct {
+		return variants, handling
+	}
+
+	selected := map[string]bool{}
+	for _, file := range files {
+		if !m.matchFile(product, file) {
+			continue
+		}
+		names := m.variantsOf(product, file)
+		if len(names) == 0 {
+			return variants, handling // Shared file: all variants
+		}
+		for _, name := range names {
+			selected[name] = true
+		}
+	}
+	if len(selected) == 0 {
+		return variants, handling
+	}
+
+	var result []config.ProductVariant
+	for _, pv := range variants {
+		if selected[pv.Variant] {
+			result = append(result, pv)
+		}
+	}
+	return result, ScopePath
+}
+
+// FilterVariantsByScope filters variants based on commit scopes.
 // - Products WITHOUT variants: always included (scope is ignored)
 // - Products WITH variants:
-//   - Empty scope -> all variants
-//   - Scoped commit matching a variant -> only that variant
-//   - Scoped commit NOT matching any variant -> all variants (treated as unscoped)
-func (m *Matcher) FilterVariantsByScope(products []string, scope string) []config.ProductVariant {
+//   - No scope or a scope naming the product -> all variants
+//   - Scopes matching variants or aliases -> every variant named
+//   - Scopes NOT matching any variant -> all variants (treated as unscoped)
+//
+// Unknown scopes select nothing if the product's scope_mode is strict or ignore.
+// Alongside known scopes they are skipped, except in strict mode, which rejects
+// the commit.
+func (m *Matcher) FilterVariantsByScope(products []string, scopes ...string) []config.ProductVariant {
 	var result []config.ProductVariant
 
 	for _, product := range products {
-		if !m.config.HasVariants(product) {
-			// No variants: include product regardless of scope
-			result = append(result, config.ProductVariant{Product: product, Variant: ""})
+		variants, _ := m.ExplainScope(product, scopes...)
+		result = append(result, variants...)
+	}
+
+	return result
+}
+
+// ExplainScope applies the FilterVariantsByScope rules to a single product and
+// returns the selected variants along with how the scope was handled
+// (ScopeIgnored, ScopeUnscoped, ScopeProduct, ScopeMatched, ScopeUnknown,
+// ScopeDropped or ScopeRejected).
+func (m *Matcher) ExplainScope(product string, scopes ...string) ([]config.ProductVariant, string) {
+	mode := m.config.Products[product].ScopeMode
+
+	// Sort the scopes into variants named, the product itself, and unknown scopes
+	named := map[string]bool{}
+	productScope, unknown, scoped := false, false, false
+	for _, scope := range scopes {
+		if scope == "" {
 			continue
 		}
+		scoped = true
+		if variant, ok := m.config.ScopeVariant(product, scope); ok {
+			named[variant] = true
+		} else if m.config.IsProductScope(product, scope) {
+			productScope = true
+		} else {
+			unknown = true
+		}
+	}
+	if unknown && mode == config.ScopeModeStrict {
+		return nil, ScopeRejected
+	}
 
-		// Product has variants - scope determines which variants
-		variants, ok := m.config.GetVariantsForProduct(product)
-		if !ok {
-			continue
+	if !m.config.HasVariants(product) {
+		// No variants: include product regardless of scope, unless unknown scopes are not allowed
+		all := []config.ProductVariant{{Product: product, Variant: ""}}
+		if !unknown || productScope {
+			return all, ScopeIgnored
 		}
+		return unknownScope(all, mode, ScopeIgnored)
+	}
 
-		if scope == "" {
-			// Unscoped commit: include all variants
-			result = append(result, variants...)
-		} else {
-			// Scoped commit: check if scope matches a known variant
-			matched := false
-			for _, pv := range variants {
-				if pv.Variant == scope {
-					result = append(result, pv)
-					matched = true
-					break
-				}
-			}
-			// If scope doesn't match any variant, treat as unscoped (include all variants)
-			if !matched {
-				result = append(result, variants...)
+	// Product has variants - scopes determine which variants
+	variants, ok := m.config.GetVariantsForProduct(product)
+	if !ok {
+		return nil, ScopeIgnored
+	}
+
+	if !scoped {
+		// Unscoped commit: include all variants
+		return variants, ScopeUnscoped
+	}
+	if productScope {
+		return variants, ScopeProduct
+	}
+
+	// Scoped commit: every variant named by a scope or alias, in config order
+	if len(named) > 0 {
+		var result []config.ProductVariant
+		for _, pv := range variants {
+			if named[pv.Variant] {
+				result = append(result, pv)
 			}
 		}
+		return result, ScopeMatched
 	}
 
-	return result
+	// If no scope matches a variant, treat as unscoped (include all variants)
+	return unknownScope(variants, mode, ScopeUnknown)
+}
+
+// unknownScope applies a product's scope mode to a scope it does not know,
+// returning all variants with the given handling for the default mode.
+func unknownScope(all []config.ProductVariant, mode, handling string) ([]config.ProductVariant, string) {
+	switch mode {
+	case config.ScopeModeStrict:
+		return nil, ScopeRejected
+	case config.ScopeModeIgnore:
+		return nil, ScopeDropped
+	default:
+		return all, handling
+	}
 }
 
 // MatchesProductVariant checks if a commit affects a specific product-variant.
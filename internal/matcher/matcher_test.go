@@ -2,6 +2,7 @@ package matcher
 
 import (
 	"sort"
+	"strings"
 	"testing"
 
 	"github.com/jimdowning-cyclops/semver-calc-go/internal/commit"
@@ -459,3 +460,423 @@ func sortPVs(pvs []config.ProductVariant) {
 		return pvs[i].Variant < pvs[j].Variant
 	})
 }
+
+func TestMatchingFiles(t *testing.T) {
+	m, _ := NewMatcher(testConfigMultiGlob())
+
+	files := []string{"apps/mobile/a.ts", "apps/web/b.ts", "libs/mobile-common/c.ts", "README.md"}
+
+	got := m.MatchingFiles("mobile", files)
+	if len(got) != 2 || got[0] != "apps/mobile/a.ts" || got[1] != "libs/mobile-common/c.ts" {
+		t.Errorf("MatchingFiles(mobile) = %v", got)
+	}
+
+	if got := m.MatchingFiles("sample-app", files); len(got) != 0 {
+		t.Errorf("MatchingFiles(sample-app) = %v, want none", got)
+	}
+
+	if got := m.MatchingFiles("unknown", files); len(got) != 0 {
+		t.Errorf("MatchingFiles(unknown) = %v, want none", got)
+	}
+}
+
+func TestExplainFiles(t *testing.T) {
+	m, _ := NewMatcher(testConfigMultiGlob())
+
+	got := m.ExplainFiles([]string{"libs/mobile-common/c.ts", "apps/web/b.ts", "README.md"})
+	want := []FileMatch{
+		{File: "libs/mobile-common/c.ts", Product: "mobile", Glob: "libs/mobile-common/**"},
+		{File: "apps/web/b.ts", Product: "web", Glob: "apps/web/**"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("ExplainFiles() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("ExplainFiles()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	// Default match-all glob is reported as "**"
+	m, _ = NewMatcher(testConfigNoGlob())
+	got = m.ExplainFiles([]string{"apps/mobile/a.ts"})
+	if len(got) != 2 || got[0].Product != "catch-all" || got[0].Glob != "**" || got[1].Product != "mobile" {
+		t.Errorf("ExplainFiles() with default glob = %+v", got)
+	}
+}
+
+func TestExplainScope(t *testing.T) {
+	m, _ := NewMatcher(testConfig())
+
+	tests := []struct {
+		name         string
+		product      string
+		scope        string
+		wantHandling string
+		wantVariants int
+	}{
+		{"no variants ignores scope", "sample-app", "customerA", ScopeIgnored, 1},
+		{"unscoped selects all variants", "mobile", "", ScopeUnscoped, 3},
+		{"scope matches variant", "mobile", "customerB", ScopeMatched, 1},
+		{"unknown scope treated as unscoped", "web", "internal", ScopeUnknown, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			variants, handling := m.ExplainScope(tt.product, tt.scope)
+			if handling != tt.wantHandling {
+				t.Errorf("handling = %q, want %q", handling, tt.wantHandling)
+			}
+			if len(variants) != tt.wantVariants {
+				t.Errorf("got %d variants, want %d", len(variants), tt.wantVariants)
+			}
+		})
+	}
+}
+
+func testConfigExclude() *config.Config {
+	return &config.Config{
+		Products: map[string]config.ProductConfig{
+			"mobile": {
+				Globs:        []string{"apps/mobile/**", "!apps/mobile/**/*.md"},
+				ExcludeGlobs: []string{"apps/mobile/test/fixtures/**"},
+				Variants:     []string{"customerA", "customerB"},
+			},
+			"docs": {
+				Globs: []string{"!**/*.md"}, // Only negations: everything else
+			},
+		},
+	}
+}
+
+func TestMatchFiles_Exclude(t *testing.T) {
+	m, err := NewMatcher(testConfigExclude())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		files    []string
+		expected []string
+	}{
+		{"included file", []string{"apps/mobile/src/app.ts"}, []string{"docs", "mobile"}},
+		{"negated glob", []string{"apps/mobile/docs/README.md"}, []string{}},
+		{"exclude_globs", []string{"apps/mobile/test/fixtures/data.json"}, []string{"docs"}},
+		{"excluded and included files", []string{"apps/mobile/docs/README.md", "apps/mobile/src/app.ts"}, []string{"docs", "mobile"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := m.MatchFiles(tt.files)
+			sort.Strings(result)
+			if len(result) != len(tt.expected) {
+				t.Fatalf("MatchFiles(%v) = %v, want %v", tt.files, result, tt.expected)
+			}
+			for i := range result {
+				if result[i] != tt.expected[i] {
+					t.Errorf("MatchFiles(%v) = %v, want %v", tt.files, result, tt.expected)
+				}
+			}
+		})
+	}
+
+	c := commit.Commit{Type: "feat", Description: "docs only"}
+	if m.MatchesProductVariant(c, []string{"apps/mobile/docs/README.md"}, config.ProductVariant{Product: "mobile", Variant: "customerA"}) {
+		t.Error("expected a commit touching only excluded files not to affect mobile")
+	}
+}
+
+func TestExcludedFiles(t *testing.T) {
+	m, _ := NewMatcher(testConfigExclude())
+
+	files := []string{"apps/mobile/docs/README.md", "apps/mobile/src/app.ts", "apps/mobile/test/fixtures/data.json", "README.md"}
+	got := m.ExcludedFiles("mobile", files)
+	want := []FileMatch{
+		{File: "apps/mobile/docs/README.md", Product: "mobile", Glob: "apps/mobile/**/*.md"},
+		{File: "apps/mobile/test/fixtures/data.json", Product: "mobile", Glob: "apps/mobile/test/fixtures/**"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("ExcludedFiles() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("ExcludedFiles()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	if got := m.MatchingFiles("mobile", files); len(got) != 1 || got[0] != "apps/mobile/src/app.ts" {
+		t.Errorf("MatchingFiles(mobile) = %v", got)
+	}
+	if got := m.ExplainFiles([]string{"apps/mobile/docs/README.md"}); len(got) != 0 {
+		t.Errorf("ExplainFiles() of excluded file = %+v, want none", got)
+	}
+}
+
+func TestMatchFiles_Ignore(t *testing.T) {
+	cfg := testConfigNoGlob()
+	cfg.Ignore = []string{".github/**", "docs/**", "**.lock", ".semver.yml"}
+	m, err := NewMatcher(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	housekeeping := []string{".github/workflows/ci.yml", "docs/guide.md", "apps/mobile/yarn.lock", ".semver.yml"}
+	if got := m.MatchFiles(housekeeping); len(got) != 0 {
+		t.Errorf("MatchFiles(housekeeping) = %v, want none", got)
+	}
+	if got := m.MatchingFiles("catch-all", housekeeping); len(got) != 0 {
+		t.Errorf("MatchingFiles(catch-all) = %v, want none", got)
+	}
+	if got := m.ExplainFiles(housekeeping); len(got) != 0 {
+		t.Errorf("ExplainFiles() = %+v, want none", got)
+	}
+
+	ignored := m.IgnoredFiles([]string{"apps/mobile/yarn.lock", "apps/mobile/a.ts"})
+	if len(ignored) != 1 || ignored[0] != (FileMatch{File: "apps/mobile/yarn.lock", Glob: "**.lock"}) {
+		t.Errorf("IgnoredFiles() = %+v", ignored)
+	}
+
+	got := m.MatchFiles([]string{"docs/guide.md", "apps/mobile/a.ts"})
+	sort.Strings(got)
+	if len(got) != 2 || got[0] != "catch-all" || got[1] != "mobile" {
+		t.Errorf("MatchFiles() with one relevant file = %v, want [catch-all mobile]", got)
+	}
+}
+
+func TestNewMatcher_InvalidIgnore(t *testing.T) {
+	cfg := testConfig()
+	cfg.Ignore = []string{"[invalid"}
+	if _, err := NewMatcher(cfg); err == nil {
+		t.Error("expected error for invalid ignore glob")
+	}
+}
+
+func testConfigVariantGlobs() *config.Config {
+	cfg, err := config.Parse(`
+products:
+  mobile:
+    globs: ["apps/mobile/shared/**", "apps/mobile/customers/{variant}/**"]
+    variants:
+      customerA:
+        globs: ["config/customerA/**"]
+      customerB:
+      internal:
+  web:
+    globs: ["apps/web/**"]
+    variants: [customerA, customerB]
+`)
+	if err != nil {
+		panic(err)
+	}
+	return cfg
+}
+
+func TestMatchCommit_VariantGlobs(t *testing.T) {
+	m, err := NewMatcher(testConfigVariantGlobs())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		scope    string
+		files    []string
+		expected []config.ProductVariant
+	}{
+		{
+			name:     "unscoped change in variant directory",
+			files:    []string{"apps/mobile/customers/customerA/theme.json"},
+			expected: []config.ProductVariant{{Product: "mobile", Variant: "customerA"}},
+		},
+		{
+			name:     "variant's own glob",
+			files:    []string{"config/customerA/app.yml", "apps/mobile/customers/customerB/theme.json"},
+			expected: []config.ProductVariant{{Product: "mobile", Variant: "customerA"}, {Product: "mobile", Variant: "customerB"}},
+		},
+		{
+			name:  "shared file selects all variants",
+			files: []string{"apps/mobile/customers/customerA/theme.json", "apps/mobile/shared/a.ts"},
+			expected: []config.ProductVariant{
+				{Product: "mobile", Variant: "customerA"},
+				{Product: "mobile", Variant: "customerB"},
+				{Product: "mobile", Variant: "internal"},
+			},
+		},
+		{
+			name:     "scope takes precedence over path",
+			scope:    "customerB",
+			files:    []string{"apps/mobile/customers/customerA/theme.json"},
+			expected: []config.ProductVariant{{Product: "mobile", Variant: "customerB"}},
+		},
+		{
+			name:     "unconfigured variant directory does not match",
+			files:    []string{"apps/mobile/customers/customerZ/theme.json"},
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := m.MatchCommit(commit.Commit{Type: "feat", Scope: tt.scope}, tt.files)
+			sortPVs(result)
+			sortPVs(tt.expected)
+			if len(result) != len(tt.expected) {
+				t.Fatalf("MatchCommit() = %v, want %v", result, tt.expected)
+			}
+			for i := range result {
+				if result[i].Product != tt.expected[i].Product || result[i].Variant != tt.expected[i].Variant {
+					t.Errorf("MatchCommit() = %v, want %v", result, tt.expected)
+				}
+			}
+		})
+	}
+
+	_, handling := m.SelectVariants("mobile", nil, []string{"apps/mobile/customers/customerA/theme.json"})
+	if handling != ScopePath {
+		t.Errorf("SelectVariants() handling = %q, want %q", handling, ScopePath)
+	}
+
+	got := m.ExplainFiles([]string{"config/customerA/app.yml"})
+	want := FileMatch{File: "config/customerA/app.yml", Product: "mobile", Variant: "customerA", Glob: "config/customerA/**"}
+	if len(got) != 1 || got[0] != want {
+		t.Errorf("ExplainFiles() = %+v, want [%+v]", got, want)
+	}
+}
+
+func TestExplainScope_AliasesAndModes(t *testing.T) {
+	cfg, err := config.Parse(`
+products:
+  mobile:
+    globs: ["apps/mobile/**"]
+    scopes: [app]
+    scope_ignore_case: true
+    variants:
+      customerA:
+        aliases: [custA, customer-a]
+      customerB:
+  web:
+    globs: ["apps/web/**"]
+    scope_mode: ignore
+    variants: [customerA, customerB]
+  api:
+    globs: ["services/api/**"]
+    scope_mode: strict
+    scopes: [backend]
+  sample-app:
+    globs: ["apps/sample/**"]
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m, err := NewMatcher(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		product      string
+		scope        string
+		wantHandling string
+		wantVariants []string
+	}{
+		{"alias selects variant", "mobile", "custA", ScopeMatched, []string{"customerA"}},
+		{"case-insensitive alias", "mobile", "Customer-A", ScopeMatched, []string{"customerA"}},
+		{"case-insensitive name", "mobile", "CUSTOMERB", ScopeMatched, []string{"customerB"}},
+		{"product scope selects all variants", "mobile", "App", ScopeProduct, []string{"customerA", "customerB"}},
+		{"unknown scope unscoped by default", "mobile", "other", ScopeUnknown, []string{"customerA", "customerB"}},
+		{"case-sensitive by default", "web", "CustomerA", ScopeDropped, nil},
+		{"ignore mode drops unknown scope", "web", "other", ScopeDropped, nil},
+		{"strict mode rejects unknown scope", "api", "other", ScopeRejected, nil},
+		{"strict mode accepts product scope", "api", "backend", ScopeIgnored, []string{""}},
+		{"strict mode accepts no scope", "api", "", ScopeIgnored, []string{""}},
+		{"no variants ignores scope by default", "sample-app", "other", ScopeIgnored, []string{""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			variants, handling := m.ExplainScope(tt.product, tt.scope)
+			if handling != tt.wantHandling {
+				t.Errorf("handling = %q, want %q", handling, tt.wantHandling)
+			}
+			var names []string
+			for _, pv := range variants {
+				names = append(names, pv.Variant)
+			}
+			sort.Strings(names)
+			if len(names) != len(tt.wantVariants) {
+				t.Fatalf("variants = %v, want %v", names, tt.wantVariants)
+			}
+			for i := range names {
+				if names[i] != tt.wantVariants[i] {
+					t.Errorf("variants = %v, want %v", names, tt.wantVariants)
+				}
+			}
+		})
+	}
+}
+
+func TestExplainScope_MultipleScopes(t *testing.T) {
+	cfg, err := config.Parse(`
+products:
+  mobile:
+    globs: ["apps/mobile/**"]
+    variants:
+      customerA:
+        aliases: [custA]
+      customerB:
+      customerC:
+  web:
+    globs: ["apps/web/**"]
+    scope_mode: strict
+    variants: [customerA, customerB]
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m, err := NewMatcher(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		product      string
+		scopes       []string
+		wantHandling string
+		wantVariants []string
+	}{
+		{"every named variant", "mobile", []string{"customerC", "customerA"}, ScopeMatched, []string{"customerA", "customerC"}},
+		{"alias and name of one variant", "mobile", []string{"custA", "customerA"}, ScopeMatched, []string{"customerA"}},
+		{"unknown scope beside a variant", "mobile", []string{"customerB", "other"}, ScopeMatched, []string{"customerB"}},
+		{"product scope selects all variants", "mobile", []string{"customerA", "mobile"}, ScopeProduct, []string{"customerA", "customerB", "customerC"}},
+		{"only unknown scopes", "mobile", []string{"x", "y"}, ScopeUnknown, []string{"customerA", "customerB", "customerC"}},
+		{"strict mode rejects any unknown scope", "web", []string{"customerA", "other"}, ScopeRejected, nil},
+		{"strict mode accepts known scopes", "web", []string{"customerA", "customerB"}, ScopeMatched, []string{"customerA", "customerB"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			variants, handling := m.ExplainScope(tt.product, tt.scopes...)
+			if handling != tt.wantHandling {
+				t.Errorf("handling = %q, want %q", handling, tt.wantHandling)
+			}
+			var names []string
+			for _, pv := range variants {
+				names = append(names, pv.Variant)
+			}
+			if strings.Join(names, ",") != strings.Join(tt.wantVariants, ",") {
+				t.Errorf("variants = %v, want %v", names, tt.wantVariants)
+			}
+		})
+	}
+
+	t.Run("MatchCommit uses parsed scopes", func(t *testing.T) {
+		c := commit.Parse("feat(customerA,customerB): shared theme", "")
+		got := m.MatchCommit(c, []string{"apps/mobile/theme.json"})
+		if len(got) != 2 || got[0].Variant != "customerA" || got[1].Variant != "customerB" {
+			t.Errorf("MatchCommit() = %v, want mobile-customerA and mobile-customerB", got)
+		}
+	})
+}
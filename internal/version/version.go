@@ -6,11 +6,15 @@ import (
 	"strings"
 )
 
-// Version represents a semantic version with major, minor, and patch components.
+// Version represents a semantic version as defined by SemVer 2.0.0.
+// Prerelease and Build hold the dot-separated identifiers without their
+// leading "-" and "+" (e.g., "rc.1" and "build.77").
 type Version struct {
-	Major int
-	Minor int
-	Patch int
+	Major      int
+	Minor      int
+	Patch      int
+	Prerelease string
+	Build      string
 }
 
 // Zero returns the zero version (0.0.0).
@@ -18,45 +22,207 @@ func Zero() Version {
 	return Version{Major: 0, Minor: 0, Patch: 0}
 }
 
-// Parse parses a version string in the format "X.Y.Z" (with optional "v" prefix).
+// Parse parses a version string in the format "X.Y.Z[-prerelease][+build]"
+// (with optional "v" prefix), following the SemVer 2.0.0 grammar.
 func Parse(s string) (Version, error) {
 	s = strings.TrimPrefix(s, "v")
 
+	var v Version
+
+	// Build metadata comes last and may itself contain "-"
+	if i := strings.Index(s, "+"); i >= 0 {
+		v.Build = s[i+1:]
+		s = s[:i]
+		if err := validateIdentifiers(v.Build, false); err != nil {
+			return Version{}, fmt.Errorf("invalid build metadata %q: %w", v.Build, err)
+		}
+	}
+
+	if i := strings.Index(s, "-"); i >= 0 {
+		v.Prerelease = s[i+1:]
+		s = s[:i]
+		if err := validateIdentifiers(v.Prerelease, true); err != nil {
+			return Version{}, fmt.Errorf("invalid pre-release %q: %w", v.Prerelease, err)
+		}
+	}
+
 	parts := strings.Split(s, ".")
 	if len(parts) != 3 {
 		return Version{}, fmt.Errorf("invalid version format: %q (expected X.Y.Z)", s)
 	}
 
-	major, err := strconv.Atoi(parts[0])
+	major, err := parseNumeric(parts[0])
 	if err != nil {
 		return Version{}, fmt.Errorf("invalid major version: %q", parts[0])
 	}
 
-	minor, err := strconv.Atoi(parts[1])
+	minor, err := parseNumeric(parts[1])
 	if err != nil {
 		return Version{}, fmt.Errorf("invalid minor version: %q", parts[1])
 	}
 
-	patch, err := strconv.Atoi(parts[2])
+	patch, err := parseNumeric(parts[2])
 	if err != nil {
 		return Version{}, fmt.Errorf("invalid patch version: %q", parts[2])
 	}
 
-	if major < 0 || minor < 0 || patch < 0 {
-		return Version{}, fmt.Errorf("version components cannot be negative")
+	v.Major, v.Minor, v.Patch = major, minor, patch
+	return v, nil
+}
+
+// parseNumeric parses a numeric identifier: digits only, no leading zeros.
+func parseNumeric(s string) (int, error) {
+	if s == "" || !isDigits(s) {
+		return 0, fmt.Errorf("not a number: %q", s)
+	}
+	if len(s) > 1 && s[0] == '0' {
+		return 0, fmt.Errorf("leading zero in %q", s)
+	}
+	return strconv.Atoi(s)
+}
+
+// validateIdentifiers checks a dot-separated list of pre-release or build identifiers.
+// Identifiers must be non-empty and contain only [0-9A-Za-z-]. Numeric pre-release
+// identifiers must not have leading zeros (build identifiers may).
+func validateIdentifiers(s string, prerelease bool) error {
+	for _, id := range strings.Split(s, ".") {
+		if id == "" {
+			return fmt.Errorf("empty identifier")
+		}
+		for _, r := range id {
+			if !(r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r == '-') {
+				return fmt.Errorf("invalid character %q in identifier %q", r, id)
+			}
+		}
+		if prerelease && isDigits(id) && len(id) > 1 && id[0] == '0' {
+			return fmt.Errorf("leading zero in numeric identifier %q", id)
+		}
 	}
+	return nil
+}
 
-	return Version{Major: major, Minor: minor, Patch: patch}, nil
+// isDigits returns true if s consists only of ASCII digits.
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
 }
 
-// String returns the version as a string in "X.Y.Z" format.
+// String returns the version as a string in "X.Y.Z[-prerelease][+build]" format.
 func (v Version) String() string {
-	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+	if v.Build != "" {
+		s += "+" + v.Build
+	}
+	return s
+}
+
+// IsPrerelease returns true if the version has pre-release identifiers.
+func (v Version) IsPrerelease() bool {
+	return v.Prerelease != ""
+}
+
+// Release returns the version with pre-release and build metadata stripped.
+func (v Version) Release() Version {
+	return Version{Major: v.Major, Minor: v.Minor, Patch: v.Patch}
+}
+
+// Compare returns -1, 0 or 1 depending on whether v has lower, equal or higher
+// precedence than other. Build metadata is ignored, and a pre-release version
+// has lower precedence than the associated release version.
+func (v Version) Compare(other Version) int {
+	if c := compareInt(v.Major, other.Major); c != 0 {
+		return c
+	}
+	if c := compareInt(v.Minor, other.Minor); c != 0 {
+		return c
+	}
+	if c := compareInt(v.Patch, other.Patch); c != 0 {
+		return c
+	}
+	return comparePrerelease(v.Prerelease, other.Prerelease)
+}
+
+// LessThan returns true if v has lower precedence than other.
+func (v Version) LessThan(other Version) bool {
+	return v.Compare(other) < 0
+}
+
+// comparePrerelease compares two pre-release strings per SemVer 2.0.0 section 11.
+func comparePrerelease(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return 1 // Release has higher precedence than pre-release
+	case b == "":
+		return -1
+	}
+
+	aIDs := strings.Split(a, ".")
+	bIDs := strings.Split(b, ".")
+	for i := 0; i < len(aIDs) && i < len(bIDs); i++ {
+		if c := compareIdentifier(aIDs[i], bIDs[i]); c != 0 {
+			return c
+		}
+	}
+	// A larger set of identifiers has higher precedence if all preceding are equal
+	return compareInt(len(aIDs), len(bIDs))
+}
+
+// compareIdentifier compares a single pre-release identifier.
+// Numeric identifiers compare numerically and always have lower precedence
+// than alphanumeric identifiers, which compare lexically in ASCII order.
+func compareIdentifier(a, b string) int {
+	aNum, bNum := isDigits(a), isDigits(b)
+	switch {
+	case aNum && bNum:
+		// Compare by length first to avoid overflow on very long identifiers
+		if c := compareInt(len(a), len(b)); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	case aNum:
+		return -1
+	case bNum:
+		return 1
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
 }
 
 // Bump returns a new version with the specified bump applied.
 // Valid bump values are "major", "minor", "patch", and "none".
+// Pre-release and build metadata are dropped. If v is a pre-release whose
+// release version already satisfies the bump (e.g., 2.0.0-rc.1 with "major"),
+// the release version is returned rather than bumping again.
 func (v Version) Bump(level string) Version {
+	if v.IsPrerelease() {
+		switch {
+		case level == "major" && v.Minor == 0 && v.Patch == 0,
+			level == "minor" && v.Patch == 0,
+			level == "patch":
+			return v.Release()
+		}
+	}
+
 	switch level {
 	case "major":
 		return Version{Major: v.Major + 1, Minor: 0, Patch: 0}
@@ -68,3 +234,96 @@ func (v Version) Bump(level string) Version {
 		return v
 	}
 }
+
+// WithPrerelease returns the release version of v with the given pre-release identifiers.
+func (v Version) WithPrerelease(prerelease string) Version {
+	r := v.Release()
+	r.Prerelease = prerelease
+	return r
+}
+
+// ValidateChannel checks that channel can be used as the leading identifier of a
+// pre-release channel version such as "1.3.0-rc.4". It must be a single,
+// non-numeric SemVer identifier.
+func ValidateChannel(channel string) error {
+	if channel == "" {
+		return fmt.Errorf("pre-release channel cannot be empty")
+	}
+	if strings.Contains(channel, ".") {
+		return fmt.Errorf("pre-release channel %q must be a single identifier", channel)
+	}
+	if isDigits(channel) {
+		return fmt.Errorf("pre-release channel %q cannot be numeric", channel)
+	}
+	if err := validateIdentifiers(channel, true); err != nil {
+		return fmt.Errorf("invalid pre-release channel %q: %w", channel, err)
+	}
+	return nil
+}
+
+// BumpLevel returns the bump level that takes from to to, considering only the
+// release versions: "major", "minor", "patch", or "none" if to is not higher.
+func BumpLevel(from, to Version) string {
+	from, to = from.Release(), to.Release()
+	switch {
+	case !from.LessThan(to):
+		return "none"
+	case to.Major != from.Major:
+		return "major"
+	case to.Minor != from.Minor:
+		return "minor"
+	default:
+		return "patch"
+	}
+}
+
+// Line is a version line that maintenance releases are pinned to: a major line such
+// as 2.x (every 2.y.z) or a minor line such as 2.4.x (every 2.4.z).
+type Line struct {
+	Major int
+	Minor int // -1 for a major line
+}
+
+// ParseLine parses a version line written as "2", "2.x", "2.4" or "2.4.x"
+// (with optional "v" prefix).
+func ParseLine(s string) (Line, error) {
+	trimmed := strings.TrimSuffix(strings.TrimPrefix(s, "v"), ".x")
+	parts := strings.Split(trimmed, ".")
+	if len(parts) > 2 {
+		return Line{}, fmt.Errorf("invalid version line %q (expected MAJOR or MAJOR.MINOR, e.g. 2 or 2.4)", s)
+	}
+
+	major, err := parseNumeric(parts[0])
+	if err != nil {
+		return Line{}, fmt.Errorf("invalid version line %q: major %w", s, err)
+	}
+	line := Line{Major: major, Minor: -1}
+	if len(parts) == 2 {
+		if line.Minor, err = parseNumeric(parts[1]); err != nil {
+			return Line{}, fmt.Errorf("invalid version line %q: minor %w", s, err)
+		}
+	}
+	return line, nil
+}
+
+// String returns the line as "2.x" or "2.4.x".
+func (l Line) String() string {
+	if l.Minor < 0 {
+		return fmt.Sprintf("%d.x", l.Major)
+	}
+	return fmt.Sprintf("%d.%d.x", l.Major, l.Minor)
+}
+
+// Contains reports whether v belongs to the line, ignoring pre-release and build metadata.
+func (l Line) Contains(v Version) bool {
+	return v.Major == l.Major && (l.Minor < 0 || v.Minor == l.Minor)
+}
+
+// MaxBump returns the highest bump level that stays on the line: "minor" for a major
+// line and "patch" for a minor line.
+func (l Line) MaxBump() string {
+	if l.Minor < 0 {
+		return "minor"
+	}
+	return "patch"
+}
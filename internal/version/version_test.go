@@ -31,6 +31,56 @@ func TestParse(t *testing.T) {
 			input: "100.200.300",
 			want:  Version{Major: 100, Minor: 200, Patch: 300},
 		},
+		{
+			name:  "pre-release",
+			input: "2.0.0-rc.1",
+			want:  Version{Major: 2, Minor: 0, Patch: 0, Prerelease: "rc.1"},
+		},
+		{
+			name:  "build metadata",
+			input: "v1.4.0+build.77",
+			want:  Version{Major: 1, Minor: 4, Patch: 0, Build: "build.77"},
+		},
+		{
+			name:  "pre-release and build metadata",
+			input: "1.0.0-alpha-1.beta+exp.sha.5114f85",
+			want:  Version{Major: 1, Minor: 0, Patch: 0, Prerelease: "alpha-1.beta", Build: "exp.sha.5114f85"},
+		},
+		{
+			name:  "build metadata with leading zeros",
+			input: "1.0.0+001",
+			want:  Version{Major: 1, Minor: 0, Patch: 0, Build: "001"},
+		},
+		{
+			name:    "invalid - leading zero in core",
+			input:   "01.2.3",
+			wantErr: true,
+		},
+		{
+			name:    "invalid - leading zero in numeric pre-release",
+			input:   "1.2.3-rc.01",
+			wantErr: true,
+		},
+		{
+			name:    "invalid - empty pre-release identifier",
+			input:   "1.2.3-rc..1",
+			wantErr: true,
+		},
+		{
+			name:    "invalid - empty build metadata",
+			input:   "1.2.3+",
+			wantErr: true,
+		},
+		{
+			name:    "invalid - bad character in pre-release",
+			input:   "1.2.3-rc_1",
+			wantErr: true,
+		},
+		{
+			name:    "invalid - negative",
+			input:   "-1.2.3",
+			wantErr: true,
+		},
 		{
 			name:    "invalid - too few parts",
 			input:   "1.2",
@@ -82,9 +132,12 @@ func TestVersion_String(t *testing.T) {
 		version Version
 		want    string
 	}{
-		{Version{1, 2, 3}, "1.2.3"},
-		{Version{0, 0, 0}, "0.0.0"},
-		{Version{10, 20, 30}, "10.20.30"},
+		{Version{Major: 1, Minor: 2, Patch: 3}, "1.2.3"},
+		{Version{Major: 0, Minor: 0, Patch: 0}, "0.0.0"},
+		{Version{Major: 10, Minor: 20, Patch: 30}, "10.20.30"},
+		{Version{Major: 1, Minor: 0, Patch: 0, Prerelease: "rc.1"}, "1.0.0-rc.1"},
+		{Version{Major: 1, Minor: 0, Patch: 0, Build: "build.7"}, "1.0.0+build.7"},
+		{Version{Major: 1, Minor: 0, Patch: 0, Prerelease: "beta", Build: "sha.abc"}, "1.0.0-beta+sha.abc"},
 	}
 
 	for _, tt := range tests {
@@ -105,51 +158,93 @@ func TestVersion_Bump(t *testing.T) {
 	}{
 		{
 			name:    "bump major",
-			version: Version{1, 2, 3},
+			version: Version{Major: 1, Minor: 2, Patch: 3},
 			level:   "major",
-			want:    Version{2, 0, 0},
+			want:    Version{Major: 2, Minor: 0, Patch: 0},
 		},
 		{
 			name:    "bump minor",
-			version: Version{1, 2, 3},
+			version: Version{Major: 1, Minor: 2, Patch: 3},
 			level:   "minor",
-			want:    Version{1, 3, 0},
+			want:    Version{Major: 1, Minor: 3, Patch: 0},
 		},
 		{
 			name:    "bump patch",
-			version: Version{1, 2, 3},
+			version: Version{Major: 1, Minor: 2, Patch: 3},
 			level:   "patch",
-			want:    Version{1, 2, 4},
+			want:    Version{Major: 1, Minor: 2, Patch: 4},
 		},
 		{
 			name:    "bump none",
-			version: Version{1, 2, 3},
+			version: Version{Major: 1, Minor: 2, Patch: 3},
 			level:   "none",
-			want:    Version{1, 2, 3},
+			want:    Version{Major: 1, Minor: 2, Patch: 3},
 		},
 		{
 			name:    "bump unknown",
-			version: Version{1, 2, 3},
+			version: Version{Major: 1, Minor: 2, Patch: 3},
 			level:   "unknown",
-			want:    Version{1, 2, 3},
+			want:    Version{Major: 1, Minor: 2, Patch: 3},
 		},
 		{
 			name:    "bump major from zero",
-			version: Version{0, 0, 0},
+			version: Version{Major: 0, Minor: 0, Patch: 0},
 			level:   "major",
-			want:    Version{1, 0, 0},
+			want:    Version{Major: 1, Minor: 0, Patch: 0},
 		},
 		{
 			name:    "bump minor from zero",
-			version: Version{0, 0, 0},
+			version: Version{Major: 0, Minor: 0, Patch: 0},
 			level:   "minor",
-			want:    Version{0, 1, 0},
+			want:    Version{Major: 0, Minor: 1, Patch: 0},
 		},
 		{
 			name:    "bump patch from zero",
-			version: Version{0, 0, 0},
+			version: Version{Major: 0, Minor: 0, Patch: 0},
 			level:   "patch",
-			want:    Version{0, 0, 1},
+			want:    Version{Major: 0, Minor: 0, Patch: 1},
+		},
+		{
+			name:    "bump major from major pre-release",
+			version: Version{Major: 2, Minor: 0, Patch: 0, Prerelease: "rc.1"},
+			level:   "major",
+			want:    Version{Major: 2, Minor: 0, Patch: 0},
+		},
+		{
+			name:    "bump major from minor pre-release",
+			version: Version{Major: 1, Minor: 3, Patch: 0, Prerelease: "rc.1"},
+			level:   "major",
+			want:    Version{Major: 2, Minor: 0, Patch: 0},
+		},
+		{
+			name:    "bump minor from minor pre-release",
+			version: Version{Major: 1, Minor: 3, Patch: 0, Prerelease: "beta.2"},
+			level:   "minor",
+			want:    Version{Major: 1, Minor: 3, Patch: 0},
+		},
+		{
+			name:    "bump minor from patch pre-release",
+			version: Version{Major: 1, Minor: 3, Patch: 1, Prerelease: "rc.1"},
+			level:   "minor",
+			want:    Version{Major: 1, Minor: 4, Patch: 0},
+		},
+		{
+			name:    "bump patch from pre-release",
+			version: Version{Major: 1, Minor: 3, Patch: 1, Prerelease: "rc.1"},
+			level:   "patch",
+			want:    Version{Major: 1, Minor: 3, Patch: 1},
+		},
+		{
+			name:    "bump drops build metadata",
+			version: Version{Major: 1, Minor: 2, Patch: 3, Build: "build.1"},
+			level:   "patch",
+			want:    Version{Major: 1, Minor: 2, Patch: 4},
+		},
+		{
+			name:    "bump none keeps pre-release",
+			version: Version{Major: 1, Minor: 3, Patch: 0, Prerelease: "rc.1"},
+			level:   "none",
+			want:    Version{Major: 1, Minor: 3, Patch: 0, Prerelease: "rc.1"},
 		},
 	}
 
@@ -168,3 +263,172 @@ func TestZero(t *testing.T) {
 		t.Errorf("Zero() = %v, want 0.0.0", z)
 	}
 }
+
+func TestVersion_Compare(t *testing.T) {
+	// Ordered by ascending precedence, from the SemVer 2.0.0 spec examples
+	ordered := []string{
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-rc.1",
+		"1.0.0",
+		"1.0.1",
+		"1.1.0",
+		"2.0.0-rc.1",
+		"2.0.0",
+	}
+
+	for i := range ordered {
+		for j := range ordered {
+			a, err := Parse(ordered[i])
+			if err != nil {
+				t.Fatalf("Parse(%q) failed: %v", ordered[i], err)
+			}
+			b, err := Parse(ordered[j])
+			if err != nil {
+				t.Fatalf("Parse(%q) failed: %v", ordered[j], err)
+			}
+
+			want := compareInt(i, j)
+			if got := a.Compare(b); got != want {
+				t.Errorf("%s.Compare(%s) = %d, want %d", ordered[i], ordered[j], got, want)
+			}
+		}
+	}
+}
+
+func TestVersion_Compare_IgnoresBuild(t *testing.T) {
+	a := Version{Major: 1, Minor: 0, Patch: 0, Build: "build.1"}
+	b := Version{Major: 1, Minor: 0, Patch: 0, Build: "build.2"}
+	if got := a.Compare(b); got != 0 {
+		t.Errorf("Compare() = %d, want 0 (build metadata must not affect precedence)", got)
+	}
+	if a.LessThan(b) || b.LessThan(a) {
+		t.Error("LessThan() should be false for versions differing only in build metadata")
+	}
+}
+
+func TestVersion_WithPrerelease(t *testing.T) {
+	v := Version{Major: 1, Minor: 3, Patch: 0, Prerelease: "beta.2", Build: "b1"}
+	got := v.WithPrerelease("rc.1")
+	want := Version{Major: 1, Minor: 3, Patch: 0, Prerelease: "rc.1"}
+	if got != want {
+		t.Errorf("WithPrerelease() = %v, want %v", got, want)
+	}
+}
+
+func TestValidateChannel(t *testing.T) {
+	tests := []struct {
+		channel string
+		wantErr bool
+	}{
+		{"rc", false},
+		{"beta", false},
+		{"pre-release", false},
+		{"", true},
+		{"rc.1", true},
+		{"1", true},
+		{"rc_1", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.channel, func(t *testing.T) {
+			err := ValidateChannel(tt.channel)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateChannel(%q) error = %v, wantErr %v", tt.channel, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBumpLevel(t *testing.T) {
+	tests := []struct {
+		from string
+		to   string
+		want string
+	}{
+		{"1.2.0", "2.0.0", "major"},
+		{"1.2.0", "1.3.0", "minor"},
+		{"1.2.0", "1.2.1", "patch"},
+		{"1.2.0", "1.2.0", "none"},
+		{"1.3.0", "1.2.0", "none"},
+		{"1.2.0", "1.3.0-rc.4", "minor"},
+		{"0.0.0", "1.0.0", "major"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.from+"->"+tt.to, func(t *testing.T) {
+			from, _ := Parse(tt.from)
+			to, _ := Parse(tt.to)
+			if got := BumpLevel(from, to); got != tt.want {
+				t.Errorf("BumpLevel(%s, %s) = %q, want %q", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		maxBump string
+		wantErr bool
+	}{
+		{"2", "2.x", "minor", false},
+		{"2.x", "2.x", "minor", false},
+		{"v2.4", "2.4.x", "patch", false},
+		{"2.4.x", "2.4.x", "patch", false},
+		{"0.9", "0.9.x", "patch", false},
+		{"2.4.1", "", "", true},
+		{"x", "", "", true},
+		{"02", "", "", true},
+		{"", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			line, err := ParseLine(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %s", line)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if line.String() != tt.want || line.MaxBump() != tt.maxBump {
+				t.Errorf("ParseLine(%q) = %s (max bump %s), want %s (max bump %s)", tt.input, line, line.MaxBump(), tt.want, tt.maxBump)
+			}
+		})
+	}
+}
+
+func TestLine_Contains(t *testing.T) {
+	major := Line{Major: 2, Minor: -1}
+	minor := Line{Major: 2, Minor: 4}
+	tests := []struct {
+		version   string
+		wantMajor bool
+		wantMinor bool
+	}{
+		{"2.4.1", true, true},
+		{"2.4.2-rc.1", true, true},
+		{"2.5.0", true, false},
+		{"3.0.0", false, false},
+		{"1.4.0", false, false},
+	}
+
+	for _, tt := range tests {
+		v, _ := Parse(tt.version)
+		if got := major.Contains(v); got != tt.wantMajor {
+			t.Errorf("%s.Contains(%s) = %v, want %v", major, tt.version, got, tt.wantMajor)
+		}
+		if got := minor.Contains(v); got != tt.wantMinor {
+			t.Errorf("%s.Contains(%s) = %v, want %v", minor, tt.version, got, tt.wantMinor)
+		}
+	}
+}
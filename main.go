@@ -2,16 +2,21 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
 	"os/exec"
+	"runtime"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/jimdowning-cyclops/semver-calc-go/internal/commit"
 	"github.com/jimdowning-cyclops/semver-calc-go/internal/config"
 	"github.com/jimdowning-cyclops/semver-calc-go/internal/git"
 	"github.com/jimdowning-cyclops/semver-calc-go/internal/matcher"
+	"github.com/jimdowning-cyclops/semver-calc-go/internal/version"
 )
 
 // VariantResult is the JSON output for a single product-variant.
@@ -23,11 +28,105 @@ type VariantResult struct {
 	Next    string `json:"next"`
 	Bump    string `json:"bump"`
 	Commits int    `json:"commits"`
+
+	// Pre-release and build metadata of the current version, if its tag carried any
+	CurrentPrerelease string `json:"currentPrerelease,omitempty"`
+	CurrentBuild      string `json:"currentBuild,omitempty"`
+
+	// Pre-release channel used for Next (e.g., "rc"), and whether Next graduates a pre-release
+	Channel   string `json:"channel,omitempty"`
+	Graduated bool   `json:"graduated,omitempty"`
+
+	// Branch rule that applied, if the current branch is known
+	Branch *BranchRule `json:"branch,omitempty"`
+
+	// Dependency (depends_on) whose bump was inherited, if it raised this result's bump
+	InheritedFrom string `json:"inheritedFrom,omitempty"`
+
+	// Problems that did not stop the calculation, such as unknown commit scopes
+	Warnings []string `json:"warnings,omitempty"`
+
+	// Relevant commits, newest first (only with --include-commits)
+	CommitDetails []CommitResult `json:"commitDetails,omitempty"`
+
+	// Why the calculation failed (only with --keep-going); other fields are then unset
+	Error string `json:"error,omitempty"`
+
+	// Fetches run to complete the git history (single target output only)
+	Fetches []FetchResult `json:"fetches,omitempty"`
+
+	relevantCommits []commit.Commit // Commits that affect this product-variant, newest first
+}
+
+// CommitResult is the JSON output for a single relevant commit.
+type CommitResult struct {
+	Hash        string   `json:"hash"`
+	Type        string   `json:"type"`
+	Scope       string   `json:"scope,omitempty"`
+	Scopes      []string `json:"scopes,omitempty"` // Individual scopes of a multi-scope commit
+	Description string   `json:"description"`
+	Breaking    bool     `json:"breaking"`
+	Files       []string `json:"files"`          // Changed files matching the product's globs
+	Bump        string   `json:"bump"`           // Bump level this commit contributes
+	Rule        string   `json:"rule,omitempty"` // Rule that caused the bump: "breaking" or the commit type
+
+	BreakingDescription string   `json:"breakingDescription,omitempty"` // Text of the BREAKING CHANGE footer
+	References          []string `json:"references,omitempty"`          // Issues from Refs, Closes, Fixes and Resolves footers
+}
+
+// BranchRule is the JSON output describing the branch rule resolved from config.
+type BranchRule struct {
+	Name       string `json:"name"`
+	Pattern    string `json:"pattern,omitempty"` // Empty if no rule matched the branch
+	Prerelease string `json:"prerelease,omitempty"`
+	MaxBump    string `json:"maxBump,omitempty"`
+	Line       string `json:"line,omitempty"` // Version line releases are pinned to, e.g. "2.x"
+}
+
+// calcOptions controls how the next version is derived from the bump level.
+type calcOptions struct {
+	Prerelease string // Pre-release channel (e.g., "rc"); empty for regular releases
+	Graduate   bool   // Promote the current pre-release to its release version without re-bumping
+	BranchName string // Branch override; detected from git when empty
+
+	IncludeCommits bool           // Add the list of relevant commits to each result
+	Parse          commit.Options // How commit messages are parsed, from the config
+	MergeOnly      bool           // Count only merge commits, by their pull request titles
+
+	Jobs      int  // Number of targets evaluated concurrently
+	KeepGoing bool // Report failed targets in their results instead of aborting
+
+	// Resolved by resolveBranchRule
+	MaxBump    string        // Highest bump level allowed; empty for no limit
+	Line       *version.Line // Version line releases are pinned to; nil for none
+	OutOfRange string        // What to do with bumps that would leave Line (config.OutOfRange*)
+	branch     *BranchRule   // Rule for the current branch, nil if the branch is unknown
 }
 
 // MultiResult is the JSON output when using config mode with --all.
 type MultiResult struct {
 	Results []VariantResult `json:"results"`
+	Fetches []FetchResult   `json:"fetches,omitempty"` // Fetches run to complete the git history
+}
+
+// FetchResult is the JSON output describing a git fetch run to complete the history.
+type FetchResult struct {
+	Command string `json:"command"` // e.g. "git fetch origin --unshallow"
+	Reason  string `json:"reason"`
+	Error   string `json:"error,omitempty"`
+}
+
+// fetchResults reports the fetches run so far, or nil if there were none.
+func fetchResults() []FetchResult {
+	var results []FetchResult
+	for _, f := range git.Fetches() {
+		r := FetchResult{Command: f.String(), Reason: f.Reason}
+		if f.Err != nil {
+			r.Error = f.Err.Error()
+		}
+		results = append(results, r)
+	}
+	return results
 }
 
 // hasEnvman returns true if envman is available for exporting outputs.
@@ -52,6 +151,11 @@ func exportVariantOutputs(result VariantResult) error {
 		"SEMVER_NEXT":     result.Next,
 		"SEMVER_BUMP":     result.Bump,
 		"SEMVER_COMMITS":  fmt.Sprintf("%d", result.Commits),
+
+		"SEMVER_CURRENT_PRERELEASE": result.CurrentPrerelease,
+		"SEMVER_CURRENT_BUILD":      result.CurrentBuild,
+		"SEMVER_CHANNEL":            result.Channel,
+		"SEMVER_GRADUATED":          fmt.Sprintf("%t", result.Graduated),
 	}
 	for key, value := range outputs {
 		if err := exportToEnvman(key, value); err != nil {
@@ -71,54 +175,151 @@ func debug(format string, args ...interface{}) {
 	}
 }
 
-func main() {
-	// Define flags
-	configFlag := flag.String("config", ".semver.yml", "Path to config file")
-	configContentFlag := flag.String("config-content", "", "Inline YAML config content (takes precedence over --config)")
-	targetFlag := flag.String("target", "", "Specific product-variant to calculate (e.g., mobile-customerA)")
-	allFlag := flag.Bool("all", false, "Calculate versions for all products in config")
-	verboseFlag := flag.Bool("verbose", false, "Enable verbose debug logging")
-	flag.Parse()
+// commonFlags holds the flags shared by the default command and subcommands.
+type commonFlags struct {
+	config        string
+	configContent string
+	target        string
+	all           bool
+	verbose       bool
+	prerelease    string
+	graduate      bool
+	branch        string
+	ref           string
+	tagSelection  string
+	fetchPolicy   string
+	remote        string
 
-	verbose = *verboseFlag
+	includeCommits bool
+	gitBackend     string
+	jobs           int
+	keepGoing      bool
+}
+
+// registerCommonFlags defines the shared flags on fs.
+func registerCommonFlags(fs *flag.FlagSet) *commonFlags {
+	f := &commonFlags{}
+	fs.StringVar(&f.config, "config", ".semver.yml", "Path to config file")
+	fs.StringVar(&f.configContent, "config-content", "", "Inline YAML config content (takes precedence over --config)")
+	fs.StringVar(&f.target, "target", "", "Specific product-variant to calculate (e.g., mobile-customerA)")
+	fs.BoolVar(&f.all, "all", false, "Calculate versions for all products in config")
+	fs.BoolVar(&f.verbose, "verbose", false, "Enable verbose debug logging")
+	fs.StringVar(&f.prerelease, "prerelease", "", "Pre-release channel for the next version (e.g., rc, beta)")
+	fs.BoolVar(&f.graduate, "graduate", false, "Promote the current pre-release to its release version")
+	fs.StringVar(&f.branch, "branch", "", "Branch name for branch rules (detected from git if not set)")
+	fs.StringVar(&f.ref, "ref", "HEAD", "Commit-ish to calculate versions at, using only the tags reachable from it")
+	fs.StringVar(&f.tagSelection, "tag-selection", "", "Last tag choice: reachable or highest (default: config, then reachable)")
+	fs.StringVar(&f.fetchPolicy, "fetch-policy", "", "Fetching of missing git history: auto, never or error (default: config, then auto)")
+	fs.StringVar(&f.remote, "remote", "", "Remote to fetch history from and push tags to (default: config, then origin)")
+	fs.BoolVar(&f.includeCommits, "include-commits", false, "Include the list of relevant commits in each result")
+	fs.StringVar(&f.gitBackend, "git-backend", git.BackendExec, "Git implementation: exec (git CLI) or go-git (in-process)")
+	fs.IntVar(&f.jobs, "jobs", runtime.NumCPU(), "Number of targets to evaluate concurrently")
+	fs.BoolVar(&f.keepGoing, "keep-going", false, "Report targets that fail and continue with the others")
+	return f
+}
 
-	// Start with flag values
-	configPath := *configFlag
-	configContent := *configContentFlag
-	target := *targetFlag
+// setup applies environment overrides, loads the config and builds the calculation options.
+// It exits the process with a usage message if the config cannot be loaded.
+func (f *commonFlags) setup() (*config.Config, calcOptions) {
+	verbose = f.verbose
 
 	// Environment variables override flags (for Bitrise step usage)
 	if c := os.Getenv("config"); c != "" {
-		configPath = c
+		f.config = c
 	}
 	if cc := os.Getenv("config_content"); cc != "" {
-		configContent = cc
+		f.configContent = cc
 	}
 	if t := os.Getenv("target"); t != "" {
-		target = t
+		f.target = t
 	}
 	if os.Getenv("verbose") == "true" || os.Getenv("verbose") == "yes" {
 		verbose = true
 	}
+	if p := os.Getenv("prerelease"); p != "" {
+		f.prerelease = p
+	}
+	if os.Getenv("graduate") == "true" || os.Getenv("graduate") == "yes" {
+		f.graduate = true
+	}
+	if b := os.Getenv("branch"); b != "" {
+		f.branch = b
+	}
+	if r := os.Getenv("ref"); r != "" {
+		f.ref = r
+	}
+	if ts := os.Getenv("tag_selection"); ts != "" {
+		f.tagSelection = ts
+	}
+	if fp := os.Getenv("fetch_policy"); fp != "" {
+		f.fetchPolicy = fp
+	}
+	if r := os.Getenv("remote"); r != "" {
+		f.remote = r
+	}
+	if os.Getenv("include_commits") == "true" || os.Getenv("include_commits") == "yes" {
+		f.includeCommits = true
+	}
+	if gb := os.Getenv("git_backend"); gb != "" {
+		f.gitBackend = gb
+	}
+	if j := os.Getenv("jobs"); j != "" {
+		n, err := strconv.Atoi(j)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: invalid jobs %q: %v\n", j, err)
+			os.Exit(1)
+		}
+		f.jobs = n
+	}
+	if os.Getenv("keep_going") == "true" || os.Getenv("keep_going") == "yes" {
+		f.keepGoing = true
+	}
+
+	debug("Config path: %s", f.config)
+	debug("Config content provided: %v", f.configContent != "")
+	debug("Target: %s", f.target)
+	debug("Git backend: %s", f.gitBackend)
+	debug("Ref: %s", f.ref)
+	debug("Jobs: %d", f.jobs)
+
+	b, err := git.NewBackend(f.gitBackend)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	git.SetBackend(b)
+
+	if f.ref != "" && f.ref != "HEAD" && git.IsGitRepository() {
+		if _, err := git.GetTagCommitHash(f.ref); err != nil {
+			fmt.Fprintf(os.Stderr, "error: --ref %s does not name a commit: %v\n", f.ref, err)
+			os.Exit(1)
+		}
+	}
+	git.SetRef(f.ref)
 
-	debug("Config path: %s", configPath)
-	debug("Config content provided: %v", configContent != "")
-	debug("Target: %s", target)
+	if f.jobs < 1 {
+		fmt.Fprintf(os.Stderr, "error: --jobs must be at least 1, got %d\n", f.jobs)
+		os.Exit(1)
+	}
+
+	if f.graduate && f.prerelease != "" {
+		fmt.Fprintln(os.Stderr, "error: --graduate and --prerelease cannot be used together")
+		os.Exit(1)
+	}
 
 	// Load config from inline content or file
 	var cfg *config.Config
-	var err error
 
-	if configContent != "" {
+	if f.configContent != "" {
 		// Inline config takes precedence
-		cfg, err = config.Parse(configContent)
+		cfg, err = config.Parse(f.configContent)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "error: failed to parse inline config: %v\n", err)
 			os.Exit(1)
 		}
 	} else {
 		// Try to load from file
-		cfg, err = config.Load(configPath)
+		cfg, err = config.Load(f.config)
 		if err != nil {
 			fmt.Fprintln(os.Stderr, "error: a config file is required")
 			fmt.Fprintln(os.Stderr, "")
@@ -127,57 +328,102 @@ func main() {
 			fmt.Fprintln(os.Stderr, "  semver-calc --target=mobile-customerA  # Calculate specific variant")
 			fmt.Fprintln(os.Stderr, "  semver-calc --config=path/to/.semver.yml")
 			fmt.Fprintln(os.Stderr, "  semver-calc --config-content='...'     # Inline YAML config")
+			fmt.Fprintln(os.Stderr, "  semver-calc tag --all [--push]         # Create tags for bumped products")
+			fmt.Fprintln(os.Stderr, "  semver-calc changelog --all [--write]  # Render changelogs for bumped products")
+			fmt.Fprintln(os.Stderr, "  semver-calc explain --target=X [--json] # Show why each commit bumped X or not")
+			os.Exit(1)
+		}
+	}
+
+	if f.prerelease != "" {
+		if err := version.ValidateChannel(f.prerelease); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
 			os.Exit(1)
 		}
 	}
 
-	if err := runConfigMode(cfg, target, *allFlag); err != nil {
+	git.SetFirstParent(cfg.WalksFirstParent())
+
+	// Flags and environment variables override the config's tag selection and fetch settings
+	if f.tagSelection == "" {
+		f.tagSelection = cfg.TagSelection
+	}
+	if err := config.ValidateTagSelection(f.tagSelection); err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
 		os.Exit(1)
 	}
-}
-
-// runConfigMode runs with a config file for file-based product detection.
-func runConfigMode(cfg *config.Config, target string, all bool) error {
-	if !git.IsGitRepository() {
-		return fmt.Errorf("not a git repository")
+	git.SetTagSelection(f.tagSelection)
+	if f.fetchPolicy == "" {
+		f.fetchPolicy = cfg.FetchPolicy
+	}
+	if err := config.ValidateFetchPolicy(f.fetchPolicy); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	if f.remote == "" {
+		f.remote = cfg.Remote
+	}
+	if f.remote == "" {
+		f.remote = "origin"
 	}
+	git.SetFetchPolicy(f.fetchPolicy)
+	git.SetRemote(f.remote)
+	debug("Fetch policy: %q, remote: %s", f.fetchPolicy, f.remote)
+	debug("Merge strategy: %s, tag selection: %q", cfg.MergeStrategy, f.tagSelection)
 
-	// Create matcher
-	m, err := matcher.NewMatcher(cfg)
-	if err != nil {
-		return fmt.Errorf("failed to create matcher: %w", err)
+	return cfg, calcOptions{
+		Prerelease:     f.prerelease,
+		Graduate:       f.graduate,
+		BranchName:     f.branch,
+		IncludeCommits: f.includeCommits,
+		Parse:          commit.Options{ScopeSeparators: cfg.ScopeSeparators, ExpandSquash: cfg.ExpandSquashMerges},
+		MergeOnly:      cfg.MergeStrategy == config.MergeStrategyMergeOnly,
+		Jobs:           f.jobs,
+		KeepGoing:      f.keepGoing,
 	}
+}
 
-	// Determine which product-variants to process
-	var targets []config.ProductVariant
-	if target != "" {
-		// Parse target like "mobile-customerA"
-		pv, err := parseTarget(cfg, target)
-		if err != nil {
-			return err
+func main() {
+	args := os.Args[1:]
+
+	// Dispatch subcommands; without one, calculate and print versions
+	if len(args) > 0 {
+		switch args[0] {
+		case "tag":
+			runTagCommand(args[1:])
+			return
+		case "changelog":
+			runChangelogCommand(args[1:])
+			return
+		case "explain":
+			runExplainCommand(args[1:])
+			return
 		}
-		targets = []config.ProductVariant{pv}
-	} else if all {
-		targets = cfg.GetAllProductVariants()
-	} else {
-		return fmt.Errorf("either --target or --all is required in config mode")
 	}
 
-	// Calculate version for each target
-	var results []VariantResult
-	for _, pv := range targets {
-		result, err := calculateForProductVariant(cfg, m, pv)
-		if err != nil {
-			return fmt.Errorf("failed to calculate for %s: %w", pv.TagName(), err)
-		}
-		results = append(results, result)
+	fs := flag.NewFlagSet("semver-calc", flag.ExitOnError)
+	f := registerCommonFlags(fs)
+	fs.Parse(args)
+
+	cfg, opts := f.setup()
+	if err := runConfigMode(cfg, f.target, f.all, opts); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// runConfigMode runs with a config file for file-based product detection.
+func runConfigMode(cfg *config.Config, target string, all bool, opts calcOptions) error {
+	results, err := calculateResults(cfg, target, all, opts)
+	if err != nil {
+		return err
 	}
 
 	// Output results
 	encoder := json.NewEncoder(os.Stdout)
 	if len(results) == 1 {
 		// Single target - output directly
+		results[0].Fetches = fetchResults()
 		if err := encoder.Encode(results[0]); err != nil {
 			return err
 		}
@@ -188,12 +434,13 @@ func runConfigMode(cfg *config.Config, target string, all bool) error {
 		}
 	} else {
 		// Multiple targets - wrap in MultiResult
-		if err := encoder.Encode(MultiResult{Results: results}); err != nil {
+		multi := MultiResult{Results: results, Fetches: fetchResults()}
+		if err := encoder.Encode(multi); err != nil {
 			return err
 		}
 		// For Bitrise with multiple results, export as JSON
 		if hasEnvman() {
-			jsonBytes, err := json.Marshal(MultiResult{Results: results})
+			jsonBytes, err := json.Marshal(multi)
 			if err != nil {
 				return err
 			}
@@ -203,6 +450,307 @@ func runConfigMode(cfg *config.Config, target string, all bool) error {
 		}
 	}
 
+	return failedResults(results)
+}
+
+// calculateResults calculates the version of each requested product-variant.
+func calculateResults(cfg *config.Config, target string, all bool, opts calcOptions) ([]VariantResult, error) {
+	if !git.IsGitRepository() {
+		return nil, fmt.Errorf("not a git repository")
+	}
+
+	if err := resolveBranchRule(cfg, &opts); err != nil {
+		return nil, err
+	}
+
+	// Create matcher
+	m, err := matcher.NewMatcher(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create matcher: %w", err)
+	}
+
+	targets, err := resolveTargets(cfg, target, all)
+	if err != nil {
+		return nil, err
+	}
+
+	_, results, errs, err := evaluateTargets(cfg, m, targets, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	failed := collectErrors(errs, opts.KeepGoing)
+	if failed != nil && !opts.KeepGoing {
+		return nil, failed
+	}
+	for i, err := range errs {
+		if err != nil {
+			pv := targets[i]
+			results[i] = VariantResult{Product: pv.Product, Variant: pv.Variant, TagName: pv.TagName(), Error: err.Error()}
+		}
+	}
+
+	return results, nil
+}
+
+// evaluateTargets loads the history of each target and calculates its result. The
+// targets' dependencies are evaluated too, so that their bumps propagate to the targets.
+// Histories, results and errors are in the order of targets; failures of individual
+// targets are returned in errs, and only a failure to walk the shared history as err.
+func evaluateTargets(cfg *config.Config, m *matcher.Matcher, targets []config.ProductVariant, opts calcOptions) ([]targetHistory, []VariantResult, []error, error) {
+	evaluated := cfg.WithDependencies(targets)
+	histories, err := loadHistories(evaluated, opts)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	bumps := make([]targetBump, len(evaluated))
+	runJobs(len(evaluated), opts.Jobs, func(i int) {
+		if histories[i].err != nil {
+			bumps[i].err = histories[i].err
+			return
+		}
+		bumps[i] = bumpFromHistory(m, cfg.GetBumpRules(evaluated[i].Product), evaluated[i], histories[i], opts)
+	})
+	propagateBumps(cfg, m, evaluated, histories, bumps, opts)
+
+	// A commit's unknown scope is reported once, on the first target it touches, rather
+	// than on every variant of the product
+	reported := map[string]bool{}
+	for i := range targets {
+		bumps[i].warnings = unreported(bumps[i].warnings, reported)
+	}
+
+	// Calculate version for each target; results keep the order of targets
+	results := make([]VariantResult, len(targets))
+	errs := make([]error, len(targets))
+	runJobs(len(targets), opts.Jobs, func(i int) {
+		pv := targets[i]
+		if bumps[i].err != nil {
+			errs[i] = fmt.Errorf("failed to calculate for %s: %w", pv.TagName(), bumps[i].err)
+			return
+		}
+		result, err := versionFromBump(pv, histories[i], bumps[i], opts)
+		if err != nil {
+			errs[i] = fmt.Errorf("failed to calculate for %s: %w", pv.TagName(), err)
+			return
+		}
+		results[i] = result
+	})
+	return histories[:len(targets)], results, errs, nil
+}
+
+// unreported returns the warnings not yet in reported, and adds them to it.
+func unreported(warnings []string, reported map[string]bool) []string {
+	var fresh []string
+	for _, w := range warnings {
+		if !reported[w] {
+			reported[w] = true
+			fresh = append(fresh, w)
+		}
+	}
+	return fresh
+}
+
+// propagateBumps raises the bump of each product-variant to the bumps it inherits from
+// the products it depends on, limited by its product's propagate policy. A dependency's
+// bump is taken from its commits since the dependent's last tag rather than its own, so
+// a change the dependent was already released with is not inherited again.
+func propagateBumps(cfg *config.Config, m *matcher.Matcher, pvs []config.ProductVariant, histories []targetHistory, bumps []targetBump, opts calcOptions) {
+	p := propagation{cfg: cfg, m: m, pvs: pvs, bumps: bumps, byProduct: map[string][]int{}, opts: opts}
+	p.opts.IncludeCommits = false
+	for i, pv := range pvs {
+		p.byProduct[pv.Product] = append(p.byProduct[pv.Product], i)
+	}
+
+	for i, pv := range pvs {
+		productCfg := cfg.Products[pv.Product]
+		for _, dep := range productCfg.DependsOn {
+			if bumps[i].err != nil {
+				break
+			}
+			depBump, err := p.dependencyBump(dep, pv.Variant, histories[i])
+			if err != nil {
+				bumps[i].err = fmt.Errorf("dependency %s failed: %w", dep, err)
+				break
+			}
+			inherited := commit.CapBump(depBump, productCfg.PropagationCap())
+			if commit.HigherBump(bumps[i].bump, inherited) != bumps[i].bump {
+				debug("%s inherits %s bump from %s (was %s)", pv.TagName(), inherited, dep, bumps[i].bump)
+				bumps[i].bump = inherited
+				bumps[i].inheritedFrom = dep
+			}
+		}
+	}
+}
+
+// propagation is what propagateBumps needs to evaluate dependencies over the commits
+// of their dependents.
+type propagation struct {
+	cfg       *config.Config
+	m         *matcher.Matcher
+	pvs       []config.ProductVariant
+	bumps     []targetBump     // Own bumps of pvs; only their errors are used
+	byProduct map[string][]int // Indexes into pvs by product
+	opts      calcOptions
+}
+
+// dependencyBump returns the bump of a dependency over the commits of h, as seen by a
+// dependent variant: that of the dependency's variant of the same name if it has one,
+// otherwise the highest bump of its variants. Bumps the dependency inherits from its
+// own dependencies over the same commits are included, so bumps propagate transitively.
+func (p *propagation) dependencyBump(dep, variant string, h targetHistory) (string, error) {
+	indexes := p.byProduct[dep]
+	for _, i := range indexes {
+		if p.pvs[i].Variant == variant {
+			indexes = []int{i}
+			break
+		}
+	}
+
+	depCfg := p.cfg.Products[dep]
+	bump := "none"
+	for _, i := range indexes {
+		if p.bumps[i].err != nil {
+			return "", p.bumps[i].err
+		}
+		tb := bumpFromHistory(p.m, p.cfg.GetBumpRules(dep), p.pvs[i], h, p.opts)
+		if tb.err != nil {
+			return "", tb.err
+		}
+		bump = commit.HigherBump(bump, tb.bump)
+		for _, next := range depCfg.DependsOn {
+			inherited, err := p.dependencyBump(next, p.pvs[i].Variant, h)
+			if err != nil {
+				return "", fmt.Errorf("dependency %s failed: %w", next, err)
+			}
+			bump = commit.HigherBump(bump, commit.CapBump(inherited, depCfg.PropagationCap()))
+		}
+	}
+	return bump, nil
+}
+
+// runJobs calls fn for each index in [0, n), running at most jobs calls at once.
+func runJobs(n, jobs int, fn func(i int)) {
+	if jobs < 1 {
+		jobs = 1
+	}
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < jobs && w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+}
+
+// collectErrors joins the non-nil errors of individual targets, in target order.
+// With keepGoing each error is also reported as a warning, as the caller continues.
+func collectErrors(errs []error, keepGoing bool) error {
+	var failed []error
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		if keepGoing {
+			fmt.Fprintf(os.Stderr, "[WARN] %v\n", err)
+		}
+		failed = append(failed, err)
+	}
+	return errors.Join(failed...)
+}
+
+// failedResults returns an error naming the results that failed with --keep-going, if any.
+func failedResults(results []VariantResult) error {
+	var names []string
+	for _, r := range results {
+		if r.Error != "" {
+			names = append(names, r.TagName)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	return fmt.Errorf("failed to calculate for %d target(s): %s", len(names), strings.Join(names, ", "))
+}
+
+// resolveTargets determines which product-variants to process.
+func resolveTargets(cfg *config.Config, target string, all bool) ([]config.ProductVariant, error) {
+	if target != "" {
+		// Parse target like "mobile-customerA"
+		pv, err := parseTarget(cfg, target)
+		if err != nil {
+			return nil, err
+		}
+		return []config.ProductVariant{pv}, nil
+	}
+	if all {
+		return cfg.GetAllProductVariants(), nil
+	}
+	return nil, fmt.Errorf("either --target or --all is required in config mode")
+}
+
+// resolveBranchRule finds the branch rule for the current branch and applies it to opts.
+// Precedence for the pre-release channel is: explicit option, branch rule, config default.
+// An explicit graduation disables the channel entirely.
+func resolveBranchRule(cfg *config.Config, opts *calcOptions) error {
+	branch := opts.BranchName
+	if branch == "" {
+		var err error
+		branch, err = git.CurrentBranch()
+		if err != nil {
+			return err
+		}
+	}
+	if branch == "" {
+		if git.Ref() == "HEAD" {
+			// Detached HEAD: fall back to the branch Bitrise checked out
+			branch = os.Getenv("BITRISE_GIT_BRANCH")
+		} else if len(cfg.Branches) > 0 {
+			// The checked-out branch says nothing about another ref, such as a tag or hash
+			fmt.Fprintf(os.Stderr, "[WARN] No branch resolved for --ref %s, so no branch rule applies (pass --branch to choose one)\n", git.Ref())
+		}
+	}
+	debug("Branch: %q", branch)
+
+	prerelease := cfg.Prerelease
+	if branch != "" {
+		opts.branch = &BranchRule{Name: branch}
+		if pattern, rule, ok := cfg.MatchBranch(branch); ok {
+			debug("Branch rule %q matched: prerelease=%q max_bump=%q line=%q", pattern, rule.Prerelease, rule.MaxBump, rule.Line)
+			line, err := rule.VersionLine(branch)
+			if err != nil {
+				return err
+			}
+			opts.branch.Pattern = pattern
+			opts.branch.Prerelease = rule.Prerelease
+			opts.branch.MaxBump = rule.MaxBump
+			if rule.Prerelease != "" {
+				prerelease = rule.Prerelease
+			}
+			opts.MaxBump = rule.MaxBump
+			if line != nil {
+				opts.branch.Line = line.String()
+				opts.Line = line
+				opts.OutOfRange = rule.OutOfRange
+			}
+		}
+	}
+	git.SetVersionLine(opts.Line)
+
+	if opts.Prerelease == "" && !opts.Graduate {
+		opts.Prerelease = prerelease
+	}
+	debug("Pre-release channel: %q, graduate: %v, max bump: %q", opts.Prerelease, opts.Graduate, opts.MaxBump)
 	return nil
 }
 
@@ -233,44 +781,259 @@ func parseTarget(cfg *config.Config, target string) (config.ProductVariant, erro
 	return config.ProductVariant{}, fmt.Errorf("unknown target %q - must be a valid product or product-variant", target)
 }
 
-// calculateForProductVariant calculates version bump for a single product-variant.
-func calculateForProductVariant(cfg *config.Config, m *matcher.Matcher, pv config.ProductVariant) (VariantResult, error) {
+// targetHistory is the last tag of a product-variant and the commits since it.
+type targetHistory struct {
+	tagName string
+	current version.Version
+	commits []git.CommitInfo
+	parsed  []commit.Commit // commits parsed as conventional commits, in the same order; expanded squash merges repeat their commit
+
+	warnings []string // Problems finding the last tag, such as higher tags that are not reachable
+
+	err error // Why the history could not be loaded; the other fields are then unset
+}
+
+// loadHistories finds the last tag of each target and the commits since it. A single
+// target reads its commits directly; several targets look up their tags using up to
+// opts.Jobs workers, then share one walk of the history back to the oldest of their tags,
+// and each commit is parsed once. Failures of individual targets are recorded in
+// their history; only a failure to walk the shared history is returned.
+func loadHistories(targets []config.ProductVariant, opts calcOptions) ([]targetHistory, error) {
+	if len(targets) == 1 {
+		h, err := loadHistory(targets[0], opts)
+		if err != nil {
+			h.err = err
+		}
+		return []targetHistory{h}, nil
+	}
+
+	histories := make([]targetHistory, len(targets))
+	runJobs(len(targets), opts.Jobs, func(i int) {
+		pv := targets[i]
+		lookup, err := git.LookupLastTag(pv.TagName())
+		if err != nil {
+			histories[i].err = fmt.Errorf("failed to find last tag: %w", err)
+			return
+		}
+		debug("Found last tag for %s: %q with version %s", pv.TagName(), lookup.Tag, lookup.Version.String())
+		histories[i] = targetHistory{tagName: lookup.Tag, current: lookup.Version, warnings: ignoredTagWarnings(lookup)}
+	})
+
+	var tags []string
+	for _, h := range histories {
+		if h.err == nil {
+			tags = append(tags, h.tagName)
+		}
+	}
+	if len(tags) == 0 {
+		return histories, nil
+	}
+
+	shared, err := git.LoadHistory(tags)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commits: %w", err)
+	}
+	debug("Loaded %d commits for %d targets", shared.Len(), len(targets))
+
+	cache := map[string][]commit.Commit{}
+	for i := range targets {
+		if histories[i].err != nil {
+			continue
+		}
+		commitInfos, err := shared.CommitsSince(histories[i].tagName)
+		if err != nil {
+			histories[i].err = fmt.Errorf("failed to get commits: %w", err)
+			continue
+		}
+		debug("Found %d commits since %q", len(commitInfos), histories[i].tagName)
+		histories[i].commits, histories[i].parsed = parseCommitInfos(commitInfos, opts, cache)
+	}
+	return histories, nil
+}
+
+// parseCommitInfos parses commits as conventional commits, reusing and filling cache by
+// hash. A commit expanded into several (see commit.ParseAll) is repeated in the returned
+// commit infos, so that they stay parallel to the parsed commits. With opts.MergeOnly,
+// only merge commits are kept, parsed from their pull request titles.
+func parseCommitInfos(commitInfos []git.CommitInfo, opts calcOptions, cache map[string][]commit.Commit) ([]git.CommitInfo, []commit.Commit) {
+	infos := make([]git.CommitInfo, 0, len(commitInfos))
+	parsed := make([]commit.Commit, 0, len(commitInfos))
+	for _, ci := range commitInfos {
+		if opts.MergeOnly && len(ci.Parents) < 2 {
+			continue
+		}
+		cs, ok := cache[ci.Hash]
+		if !ok {
+			subject, body := ci.Subject, ci.Body
+			if opts.MergeOnly {
+				subject, body = commit.MergeTitle(subject, body)
+			}
+			cs = commit.ParseAll(subject, body, opts.Parse)
+			for i := range cs {
+				cs[i].Hash = ci.Hash
+			}
+			cache[ci.Hash] = cs
+		}
+		for _, c := range cs {
+			infos = append(infos, ci)
+			parsed = append(parsed, c)
+		}
+	}
+	return infos, parsed
+}
+
+// loadHistory finds the last tag for a product-variant and the commits since it.
+func loadHistory(pv config.ProductVariant, opts calcOptions) (targetHistory, error) {
 	debug("Calculating for product=%s variant=%s tagPrefix=%s", pv.Product, pv.Variant, pv.TagPrefix)
 	debug("TagName() returns: %q", pv.TagName())
 
 	// Find last tag for this product-variant
-	tagName, currentVersion, err := git.FindLastTagByPrefix(pv.TagName())
+	lookup, err := git.LookupLastTag(pv.TagName())
 	if err != nil {
-		return VariantResult{}, fmt.Errorf("failed to find last tag: %w", err)
+		return targetHistory{}, fmt.Errorf("failed to find last tag: %w", err)
 	}
-	debug("Found last tag: %q with version %s", tagName, currentVersion.String())
+	debug("Found last tag: %q with version %s", lookup.Tag, lookup.Version.String())
 
 	// Get commits with files since that tag
-	commitInfos, err := git.GetCommitsSinceWithFiles(tagName)
+	commitInfos, err := git.GetCommitsSinceWithFiles(lookup.Tag)
 	if err != nil {
-		return VariantResult{}, fmt.Errorf("failed to get commits: %w", err)
+		return targetHistory{}, fmt.Errorf("failed to get commits: %w", err)
 	}
 	debug("Found %d commits since tag", len(commitInfos))
 
+	commitInfos, parsed := parseCommitInfos(commitInfos, opts, map[string][]commit.Commit{})
+	return targetHistory{
+		tagName:  lookup.Tag,
+		current:  lookup.Version,
+		commits:  commitInfos,
+		parsed:   parsed,
+		warnings: ignoredTagWarnings(lookup),
+	}, nil
+}
+
+// ignoredTagWarnings reports the tags the lookup of a product-variant's last tag
+// ignored, because they are not reachable from the ref despite a higher version.
+func ignoredTagWarnings(lookup git.TagLookup) []string {
+	ignored := lookup.Unreachable
+	if len(ignored) == 0 {
+		return nil
+	}
+	if git.IsShallowRepo() {
+		return []string{fmt.Sprintf("ignoring %s: not reachable from %s in this shallow clone (fetch the full history to use them)", strings.Join(ignored, ", "), git.Ref())}
+	}
+	return []string{fmt.Sprintf("ignoring %s: not reachable from %s (set tag_selection to highest to use the highest tag anyway)", strings.Join(ignored, ", "), git.Ref())}
+}
+
+// targetBump is the bump level of a product-variant and the commits that caused it.
+type targetBump struct {
+	bump            string // Bump level before branch caps, including inherited bumps
+	inheritedFrom   string // Dependency whose bump raised bump, if any
+	relevantCommits []commit.Commit
+	commitDetails   []CommitResult
+	warnings        []string
+
+	err error // Why the bump could not be determined
+}
+
+// bumpFromHistory determines the bump level of a product-variant from its loaded history,
+// applying its product's bump rules. Reverts paired with the commit they revert since
+// the last tag cancel out and count for neither the bump nor the changelog.
+func bumpFromHistory(m *matcher.Matcher, rules commit.Rules, pv config.ProductVariant, h targetHistory, opts calcOptions) targetBump {
+	cancelled := commit.CancelReverts(h.parsed)
+
 	// Filter commits that affect this product-variant
 	var relevantCommits []commit.Commit
-	for _, ci := range commitInfos {
-		c := commit.Parse(ci.Subject, ci.Body)
-		c.Hash = ci.Hash
+	var commitDetails []CommitResult
+	var warnings []string
+	for i, ci := range h.commits {
+		c := h.parsed[i]
+
+		// Report scopes the product does not know, for commits that touch it, even where
+		// the changed files then select the variants
+		if c.Scope != "" && len(m.MatchingFiles(pv.Product, ci.Files)) > 0 {
+			switch _, handling := m.ExplainScope(pv.Product, c.ScopeList()...); handling {
+			case matcher.ScopeUnknown:
+				warnings = append(warnings, fmt.Sprintf("commit %s: unknown scope %q for %s, treated as unscoped", c.Hash[:7], c.Scope, pv.Product))
+			case matcher.ScopeDropped:
+				warnings = append(warnings, fmt.Sprintf("commit %s: unknown scope %q for %s, commit ignored", c.Hash[:7], c.Scope, pv.Product))
+			case matcher.ScopeRejected:
+				return targetBump{err: fmt.Errorf("commit %s: unknown scope %q for %s (scope_mode is strict)", c.Hash[:7], c.Scope, pv.Product)}
+			}
+		}
 
 		// Check if this commit affects this product-variant
-		if m.MatchesProductVariant(c, ci.Files, pv) {
+		if cancelled[i] {
+			debug("  Reverted commit: %s %s (type=%s)", c.Hash[:7], c.Description, c.Type)
+		} else if m.MatchesProductVariant(c, ci.Files, pv) {
 			debug("  Relevant commit: %s %s (type=%s)", c.Hash[:7], c.Description, c.Type)
 			relevantCommits = append(relevantCommits, c)
+			if opts.IncludeCommits {
+				commitDetails = append(commitDetails, newCommitResult(m, rules, pv, c, ci.Files))
+			}
+		} else if verbose {
+			for _, ig := range m.IgnoredFiles(ci.Files) {
+				debug("  Ignored file: %s %s (ignored by %q)", c.Hash[:7], ig.File, ig.Glob)
+			}
+			for _, ex := range m.ExcludedFiles(pv.Product, ci.Files) {
+				debug("  Excluded file: %s %s (excluded by %q)", c.Hash[:7], ex.File, ex.Glob)
+			}
 		}
 	}
-	debug("Filtered to %d relevant commits", len(relevantCommits))
+	debug("Filtered to %d relevant commits for %s", len(relevantCommits), pv.TagName())
 
-	// Determine bump level
-	bump := commit.DetermineBump(relevantCommits)
+	return targetBump{
+		bump:            rules.DetermineBump(relevantCommits),
+		relevantCommits: relevantCommits,
+		commitDetails:   commitDetails,
+		warnings:        warnings,
+	}
+}
+
+// versionFromBump calculates the next version of a product-variant from its bump level.
+func versionFromBump(pv config.ProductVariant, h targetHistory, tb targetBump, opts calcOptions) (VariantResult, error) {
+	currentVersion := h.current
+	var err error
+
+	// Keep to the branch's version line, then apply the branch rule's cap
+	bump := tb.bump
+	warnings := append(append([]string(nil), h.warnings...), tb.warnings...)
+	if opts.Line != nil {
+		if bump != "none" && !opts.Line.Contains(currentVersion) {
+			return VariantResult{}, fmt.Errorf("no %s tag on version line %s of branch %s: tag the line's first release (e.g. %s) to start it",
+				pv.TagName(), opts.Line, opts.branch.Name, git.FormatTagName(pv.TagName(), version.Version{Major: opts.Line.Major, Minor: max(opts.Line.Minor, 0)}))
+		}
+		if capped := commit.CapBump(bump, opts.Line.MaxBump()); capped != bump {
+			if opts.OutOfRange == config.OutOfRangeFail {
+				return VariantResult{}, fmt.Errorf("%s bump of %s %s would leave version line %s of branch %s (out_of_range: fail)",
+					bump, pv.TagName(), currentVersion, opts.Line, opts.branch.Name)
+			}
+			debug("Bump level %s downgraded to %s to stay on version line %s", bump, capped, opts.Line)
+			warnings = append(warnings, fmt.Sprintf("%s bump downgraded to %s to stay on version line %s", bump, capped, opts.Line))
+			bump = capped
+		}
+	}
+	if capped := commit.CapBump(bump, opts.MaxBump); capped != bump {
+		debug("Bump level %s capped to %s by branch rule", bump, capped)
+		bump = capped
+	}
 	nextVersion := currentVersion.Bump(bump)
 	debug("Bump level: %s, next version: %s", bump, nextVersion.String())
 
+	var graduated bool
+	switch {
+	case opts.Graduate:
+		bump, nextVersion, graduated, err = graduateVersion(pv, currentVersion)
+		if err != nil {
+			return VariantResult{}, err
+		}
+	case opts.Prerelease != "" && bump != "none":
+		nextVersion, err = nextPrereleaseVersion(pv, nextVersion, opts.Prerelease)
+		if err != nil {
+			return VariantResult{}, err
+		}
+		debug("Pre-release version: %s", nextVersion.String())
+	}
+
 	return VariantResult{
 		Product: pv.Product,
 		Variant: pv.Variant,
@@ -278,6 +1041,71 @@ func calculateForProductVariant(cfg *config.Config, m *matcher.Matcher, pv confi
 		Current: currentVersion.String(),
 		Next:    nextVersion.String(),
 		Bump:    bump,
-		Commits: len(relevantCommits),
+		Commits: len(tb.relevantCommits),
+
+		CurrentPrerelease: currentVersion.Prerelease,
+		CurrentBuild:      currentVersion.Build,
+		Channel:           opts.Prerelease,
+		Graduated:         graduated,
+		Branch:            opts.branch,
+		CommitDetails:     tb.commitDetails,
+		InheritedFrom:     tb.inheritedFrom,
+		Warnings:          warnings,
+
+		relevantCommits: tb.relevantCommits,
 	}, nil
 }
+
+// newCommitResult describes a relevant commit for JSON output.
+func newCommitResult(m *matcher.Matcher, rules commit.Rules, pv config.ProductVariant, c commit.Commit, files []string) CommitResult {
+	bump, rule := rules.BumpFor(c)
+	matched := m.MatchingFiles(pv.Product, files)
+	if matched == nil {
+		matched = []string{}
+	}
+	return CommitResult{
+		Hash:        c.Hash,
+		Type:        c.Type,
+		Scope:       c.Scope,
+		Scopes:      c.Scopes,
+		Description: c.Description,
+		Breaking:    c.Breaking,
+		Files:       matched,
+		Bump:        bump,
+		Rule:        rule,
+
+		BreakingDescription: c.BreakingDescription,
+		References:          c.References(),
+	}
+}
+
+// nextPrereleaseVersion returns the next "{base}-{channel}.N" version, where N is one
+// more than the highest existing pre-release tag for that base and channel.
+func nextPrereleaseVersion(pv config.ProductVariant, base version.Version, channel string) (version.Version, error) {
+	n, err := git.FindLastPrereleaseNumber(pv.TagName(), base, channel)
+	if err != nil {
+		return version.Version{}, fmt.Errorf("failed to find pre-release tags: %w", err)
+	}
+	debug("Highest existing %s pre-release for %s: %d", channel, base.String(), n)
+	return base.WithPrerelease(fmt.Sprintf("%s.%d", channel, n+1)), nil
+}
+
+// graduateVersion promotes a pre-release (e.g., 1.3.0-rc.4) to its release version (1.3.0)
+// without re-bumping. The reported bump level is relative to the last release tag.
+// If the current version is not a pre-release, there is nothing to graduate.
+func graduateVersion(pv config.ProductVariant, current version.Version) (string, version.Version, bool, error) {
+	if !current.IsPrerelease() {
+		debug("Current version %s is not a pre-release, nothing to graduate", current.String())
+		return "none", current, false, nil
+	}
+
+	_, lastRelease, err := git.FindLastReleaseTagByPrefix(pv.TagName())
+	if err != nil {
+		return "", version.Version{}, false, fmt.Errorf("failed to find last release tag: %w", err)
+	}
+
+	next := current.Release()
+	bump := version.BumpLevel(lastRelease, next)
+	debug("Graduating %s to %s (bump %s from %s)", current.String(), next.String(), bump, lastRelease.String())
+	return bump, next, true, nil
+}
@@ -0,0 +1,682 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"text/template"
+
+	"github.com/jimdowning-cyclops/semver-calc-go/internal/changelog"
+	"github.com/jimdowning-cyclops/semver-calc-go/internal/config"
+	"github.com/jimdowning-cyclops/semver-calc-go/internal/git"
+	"github.com/jimdowning-cyclops/semver-calc-go/internal/matcher"
+)
+
+// testRepo creates a git repository in a temporary directory and makes it the
+// working directory for the rest of the test.
+func testRepo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	runGit(t, dir, "init", "-q", "-b", "main")
+	runGit(t, dir, "config", "user.email", "test@test.com")
+	runGit(t, dir, "config", "user.name", "Test User")
+	runGit(t, dir, "config", "tag.gpgSign", "false")
+	t.Chdir(dir)
+	return dir
+}
+
+// runGit runs a git command in dir and returns its output, failing the test on error.
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v: %s", strings.Join(args, " "), err, out)
+	}
+	return string(out)
+}
+
+// commitFiles writes each file with unique content and commits them with message.
+func commitFiles(t *testing.T, dir, message string, files ...string) {
+	t.Helper()
+	for _, f := range files {
+		path := filepath.Join(dir, f)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		content := message + "\n"
+		if old, err := os.ReadFile(path); err == nil {
+			content = string(old) + content
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		runGit(t, dir, "add", f)
+	}
+	runGit(t, dir, "commit", "-q", "-m", message)
+}
+
+// parseConfig parses a config, failing the test on error.
+func parseConfig(t *testing.T, content string) *config.Config {
+	t.Helper()
+	cfg, err := config.Parse(content)
+	if err != nil {
+		t.Fatalf("invalid config: %v", err)
+	}
+	return cfg
+}
+
+// captureStdout runs fn with os.Stdout redirected and returns what it wrote.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	return captureFile(t, &os.Stdout, fn)
+}
+
+// captureStderr runs fn with os.Stderr redirected and returns what it wrote.
+func captureStderr(t *testing.T, fn func()) string {
+	t.Helper()
+	return captureFile(t, &os.Stderr, fn)
+}
+
+// captureFile runs fn with *f redirected to a pipe and returns what it wrote.
+func captureFile(t *testing.T, f **os.File, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := *f
+	*f = w
+	done := make(chan string)
+	go func() {
+		out, _ := io.ReadAll(r)
+		done <- string(out)
+	}()
+	defer func() {
+		*f = old
+	}()
+	fn()
+	w.Close()
+	return <-done
+}
+
+const tagTestConfig = `
+products:
+  app: {globs: ["app/**"]}
+  web: {globs: ["web/**"]}
+`
+
+// tagTestRepo creates a repository with app and web released at 1.0.0 and a feature
+// for each since, pushing main to a bare "upstream" remote. It returns the remote's path.
+func tagTestRepo(t *testing.T) string {
+	t.Helper()
+	remote := t.TempDir()
+	runGit(t, remote, "init", "-q", "--bare")
+
+	dir := testRepo(t)
+	commitFiles(t, dir, "chore: initial", "app/a.txt", "web/w.txt")
+	runGit(t, dir, "tag", "app-v1.0.0")
+	runGit(t, dir, "tag", "web-v1.0.0")
+	commitFiles(t, dir, "feat: app feature", "app/a.txt")
+	commitFiles(t, dir, "fix: web fix", "web/w.txt")
+	runGit(t, dir, "remote", "add", "upstream", remote)
+	runGit(t, dir, "push", "-q", "upstream", "main")
+	return remote
+}
+
+func TestRunTag_PushesToRemote(t *testing.T) {
+	remote := tagTestRepo(t)
+	cfg := parseConfig(t, tagTestConfig)
+
+	tagOpts := tagOptions{
+		Message: template.Must(template.New("message").Parse(defaultTagMessage)),
+		Push:    true,
+		Remote:  "upstream",
+	}
+	out := captureStdout(t, func() {
+		if err := runTag(cfg, "", true, calcOptions{Jobs: 1}, tagOpts); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+	if !strings.Contains(out, `"tag":"app-v1.1.0"`) || !strings.Contains(out, `"tag":"web-v1.0.1"`) || !strings.Contains(out, `"pushed":true`) {
+		t.Errorf("unexpected output: %s", out)
+	}
+
+	got := strings.Fields(runGit(t, remote, "tag", "-l"))
+	if strings.Join(got, " ") != "app-v1.1.0 web-v1.0.1" {
+		t.Errorf("expected the new tags on the remote, got %v", got)
+	}
+	if msg := runGit(t, ".", "tag", "-l", "--format=%(contents:subject)", "app-v1.1.0"); strings.TrimSpace(msg) != "Release app 1.1.0" {
+		t.Errorf("unexpected tag message %q", msg)
+	}
+}
+
+func TestRunTag_RollsBackOnPushFailure(t *testing.T) {
+	remote := tagTestRepo(t)
+	cfg := parseConfig(t, tagTestConfig)
+
+	tagOpts := tagOptions{
+		Message: template.Must(template.New("message").Parse(defaultTagMessage)),
+		Push:    true,
+		Remote:  "nonexistent",
+	}
+	captureStdout(t, func() {
+		err := runTag(cfg, "", true, calcOptions{Jobs: 1}, tagOpts)
+		if err == nil || !strings.Contains(err.Error(), "deleted the tags created so far: app-v1.1.0, web-v1.0.1") {
+			t.Errorf("expected push error with rollback, got %v", err)
+		}
+	})
+
+	if got := strings.Fields(runGit(t, ".", "tag", "-l")); strings.Join(got, " ") != "app-v1.0.0 web-v1.0.0" {
+		t.Errorf("expected the created tags to be deleted, got %v", got)
+	}
+	if got := runGit(t, remote, "tag", "-l"); got != "" {
+		t.Errorf("expected no tags on the remote, got %q", got)
+	}
+}
+
+func TestRunTag_RollsBackOnCreateFailure(t *testing.T) {
+	tagTestRepo(t)
+	cfg := parseConfig(t, tagTestConfig)
+
+	// A tag named like a directory of web-v1.0.1 passes the checks but makes creating
+	// web-v1.0.1 fail, after app-v1.1.0 was created
+	runGit(t, ".", "tag", "web-v1.0.1/blocker")
+
+	tagOpts := tagOptions{Message: template.Must(template.New("message").Parse(defaultTagMessage))}
+	captureStdout(t, func() {
+		err := runTag(cfg, "", true, calcOptions{Jobs: 1}, tagOpts)
+		if err == nil || !strings.Contains(err.Error(), "deleted the tags created so far: app-v1.1.0") {
+			t.Errorf("expected create error with rollback, got %v", err)
+		}
+	})
+
+	if got := strings.Fields(runGit(t, ".", "tag", "-l")); strings.Join(got, " ") != "app-v1.0.0 web-v1.0.0 web-v1.0.1/blocker" {
+		t.Errorf("expected app-v1.1.0 to be deleted, got %v", got)
+	}
+}
+
+const jobsTestConfig = `
+products:
+  api: {globs: ["api/**"]}
+  app:
+    globs: ["app/**"]
+    variants: [free, pro]
+  docs: {globs: ["docs/**"]}
+  lib: {globs: ["lib/**"]}
+  web: {globs: ["web/**"]}
+`
+
+// jobsTestRepo creates a repository where every product of jobsTestConfig has its
+// own release and changes since, and app has a commit with an unknown scope.
+func jobsTestRepo(t *testing.T) string {
+	t.Helper()
+	dir := testRepo(t)
+	commitFiles(t, dir, "chore: initial", "api/a", "app/a", "docs/a", "lib/a", "web/a")
+	for _, tag := range []string{"api-v1.0.0", "app-free-v0.3.0", "app-pro-v2.1.0", "docs-v0.1.0", "lib-v3.0.0"} {
+		runGit(t, dir, "tag", tag)
+	}
+	commitFiles(t, dir, "feat: api feature", "api/a")
+	commitFiles(t, dir, "fix: docs fix", "docs/a")
+	commitFiles(t, dir, "feat!: lib rewrite", "lib/a")
+	commitFiles(t, dir, "fix(billing): app fix", "app/a")
+	commitFiles(t, dir, "feat: web feature", "web/a")
+	return dir
+}
+
+// resultSummary lists each result's product-variant and next version, in order.
+func resultSummary(results []VariantResult) string {
+	var fields []string
+	for _, r := range results {
+		name := r.Product
+		if r.Variant != "" {
+			name += "-" + r.Variant
+		}
+		fields = append(fields, name+"@"+r.Next)
+	}
+	return strings.Join(fields, " ")
+}
+
+func TestCalculateResults_JobsKeepOrder(t *testing.T) {
+	jobsTestRepo(t)
+	cfg := parseConfig(t, jobsTestConfig)
+
+	want := "api@1.1.0 app-free@0.3.1 app-pro@2.1.1 docs@0.1.1 lib@4.0.0 web@0.1.0"
+	for _, jobs := range []int{1, 8} {
+		results, err := calculateResults(cfg, "", true, calcOptions{Jobs: jobs})
+		if err != nil {
+			t.Fatalf("--jobs %d: unexpected error: %v", jobs, err)
+		}
+		if got := resultSummary(results); got != want {
+			t.Errorf("--jobs %d: expected %s, got %s", jobs, want, got)
+		}
+	}
+}
+
+// failingTagsBackend is a git.Backend whose tag listing fails once the history has been
+// walked, so that lookups made while calculating a version fail, such as that of the
+// last release tag when graduating, but finding the last tags does not.
+type failingTagsBackend struct {
+	git.Backend
+	walked atomic.Bool
+}
+
+func (b *failingTagsBackend) Log(since string, opts git.LogOptions) ([]git.CommitInfo, error) {
+	b.walked.Store(true)
+	return b.Backend.Log(since, opts)
+}
+
+func (b *failingTagsBackend) Tags(merged string) ([]string, error) {
+	if b.walked.Load() {
+		return nil, errors.New("tags unavailable")
+	}
+	return b.Backend.Tags(merged)
+}
+
+func TestRunConfigMode_KeepGoing(t *testing.T) {
+	dir := jobsTestRepo(t)
+	// Only docs is at a pre-release, so only docs looks up its last release to graduate
+	runGit(t, dir, "tag", "docs-v0.2.0-rc.1")
+	cfg := parseConfig(t, jobsTestConfig)
+
+	old := git.CurrentBackend()
+	t.Cleanup(func() { git.SetBackend(old) })
+	opts := calcOptions{Graduate: true, Jobs: 4}
+
+	// Without --keep-going the failed target aborts the whole run
+	git.SetBackend(&failingTagsBackend{Backend: old})
+	if _, err := calculateResults(cfg, "", true, opts); err == nil || !strings.Contains(err.Error(), "tags unavailable") {
+		t.Fatalf("expected docs to fail the run, got %v", err)
+	}
+
+	git.SetBackend(&failingTagsBackend{Backend: old})
+	opts.KeepGoing = true
+	var runErr error
+	out := captureStdout(t, func() {
+		runErr = runConfigMode(cfg, "", true, opts)
+	})
+	if runErr == nil || runErr.Error() != "failed to calculate for 1 target(s): docs" {
+		t.Errorf("expected the failed target to be reported, got %v", runErr)
+	}
+
+	var multi MultiResult
+	if err := json.Unmarshal([]byte(out), &multi); err != nil {
+		t.Fatalf("invalid output %q: %v", out, err)
+	}
+	if got, want := resultSummary(multi.Results), "api@1.0.0 app-free@0.3.0 app-pro@2.1.0 docs@ lib@3.0.0 web@0.0.0"; got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+	for _, r := range multi.Results {
+		failed := r.Product == "docs"
+		if (r.Error != "") != failed {
+			t.Errorf("%s: unexpected error %q", r.TagName, r.Error)
+		}
+		if failed && !strings.Contains(r.Error, "failed to find last release tag: tags unavailable") {
+			t.Errorf("%s: expected the lookup error, got %q", r.TagName, r.Error)
+		}
+	}
+}
+
+const dependencyTestConfig = `
+products:
+  lib: {globs: ["lib/**"]}
+  core:
+    globs: ["core/**"]
+    depends_on: [lib]
+  app:
+    globs: ["app/**"]
+    depends_on: [core]
+  web:
+    globs: ["web/**"]
+    depends_on: [lib]
+    propagate: patch
+`
+
+// dependencyTestRepo creates a repository with every product of dependencyTestConfig
+// released at 1.0.0 and a feature in lib since.
+func dependencyTestRepo(t *testing.T) string {
+	t.Helper()
+	dir := testRepo(t)
+	commitFiles(t, dir, "chore: initial", "lib/a", "core/a", "app/a", "web/a")
+	for _, tag := range []string{"lib-v1.0.0", "core-v1.0.0", "app-v1.0.0", "web-v1.0.0"} {
+		runGit(t, dir, "tag", tag)
+	}
+	commitFiles(t, dir, "feat: lib feature", "lib/a")
+	return dir
+}
+
+// resultsByProduct calculates every target, keyed by product.
+func resultsByProduct(t *testing.T, cfg *config.Config) map[string]VariantResult {
+	t.Helper()
+	results, err := calculateResults(cfg, "", true, calcOptions{Jobs: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	byProduct := map[string]VariantResult{}
+	for _, r := range results {
+		byProduct[r.Product] = r
+	}
+	return byProduct
+}
+
+func TestPropagateBumps(t *testing.T) {
+	dependencyTestRepo(t)
+	results := resultsByProduct(t, parseConfig(t, dependencyTestConfig))
+
+	tests := []struct {
+		product, bump, inheritedFrom string
+	}{
+		{"lib", "minor", ""},
+		{"core", "minor", "lib"},
+		{"app", "minor", "core"}, // Transitively through core
+		{"web", "patch", "lib"},  // Capped by propagate: patch
+	}
+	for _, tt := range tests {
+		r := results[tt.product]
+		if r.Bump != tt.bump || r.InheritedFrom != tt.inheritedFrom {
+			t.Errorf("%s: expected bump %s inherited from %q, got %s from %q", tt.product, tt.bump, tt.inheritedFrom, r.Bump, r.InheritedFrom)
+		}
+	}
+}
+
+func TestPropagateBumps_DependentAlreadyReleased(t *testing.T) {
+	dir := dependencyTestRepo(t)
+	// core and app were released after the lib feature; web was not
+	runGit(t, dir, "tag", "core-v1.1.0")
+	runGit(t, dir, "tag", "app-v1.1.0")
+	commitFiles(t, dir, "fix: app fix", "app/a")
+
+	results := resultsByProduct(t, parseConfig(t, dependencyTestConfig))
+
+	tests := []struct {
+		product, bump, inheritedFrom string
+	}{
+		{"lib", "minor", ""},
+		{"core", "none", ""},
+		{"app", "patch", ""},
+		{"web", "patch", "lib"},
+	}
+	for _, tt := range tests {
+		r := results[tt.product]
+		if r.Bump != tt.bump || r.InheritedFrom != tt.inheritedFrom {
+			t.Errorf("%s: expected bump %s inherited from %q, got %s from %q", tt.product, tt.bump, tt.inheritedFrom, r.Bump, r.InheritedFrom)
+		}
+	}
+}
+
+func TestPropagateBumps_DependencyReleasedSinceDependent(t *testing.T) {
+	dir := dependencyTestRepo(t)
+	// lib was released with its feature, but core has not been released since
+	runGit(t, dir, "tag", "lib-v1.1.0")
+
+	results := resultsByProduct(t, parseConfig(t, dependencyTestConfig))
+	if r := results["lib"]; r.Bump != "none" {
+		t.Errorf("lib: expected no bump, got %s", r.Bump)
+	}
+	if r := results["core"]; r.Bump != "minor" || r.InheritedFrom != "lib" {
+		t.Errorf("core: expected minor bump inherited from lib, got %s from %q", r.Bump, r.InheritedFrom)
+	}
+}
+
+func TestRunChangelog_InheritedOnly(t *testing.T) {
+	dependencyTestRepo(t)
+	tmpl, err := changelog.ParseTemplate("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var runErr error
+	out := captureStdout(t, func() {
+		runErr = runChangelog(parseConfig(t, dependencyTestConfig), "web", false, calcOptions{}, changelogOptions{Template: tmpl})
+	})
+	if runErr != nil {
+		t.Fatalf("unexpected error: %v", runErr)
+	}
+	// web has no commits of its own, only the bump inherited from lib
+	if !strings.Contains(out, "## web 1.0.1") || !strings.Contains(out, "### Dependencies\n\n- changes in lib\n") {
+		t.Errorf("expected the inherited bump to name lib, got:\n%s", out)
+	}
+}
+
+func TestBumpFromHistory_UnknownScopeWarningWithPathVariants(t *testing.T) {
+	dir := testRepo(t)
+	commitFiles(t, dir, "chore: initial", "app/src/a", "app/free/a", "app/pro/a")
+	runGit(t, dir, "tag", "app-free-v1.0.0")
+	runGit(t, dir, "tag", "app-pro-v1.0.0")
+	// The unknown scope leaves the variants to be selected by the changed files
+	commitFiles(t, dir, "fix(billing): free fix", "app/free/a")
+
+	cfg := parseConfig(t, `
+products:
+  app:
+    globs: ["app/src/**", "app/{variant}/**"]
+    variants: [free, pro]
+`)
+	results, err := calculateResults(cfg, "", true, calcOptions{Jobs: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, want := resultSummary(results), "app-free@1.0.1 app-pro@1.0.0"; got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+	// The warning is reported once for the commit, not once per variant
+	if w := results[0].Warnings; len(w) != 1 || !strings.Contains(w[0], `unknown scope "billing" for app`) {
+		t.Errorf("%s: expected the unknown scope warning, got %q", results[0].TagName, w)
+	}
+	if w := results[1].Warnings; len(w) != 0 {
+		t.Errorf("%s: expected no repeated warning, got %q", results[1].TagName, w)
+	}
+}
+
+func TestRunExplain_KeepGoingShowsFailedTargets(t *testing.T) {
+	jobsTestRepo(t)
+	// app's commit with the unknown scope "billing" is rejected in strict mode
+	cfg := parseConfig(t, strings.Replace(jobsTestConfig, "variants: [free, pro]", "scope_mode: strict\n    variants: [free, pro]", 1))
+	opts := calcOptions{Jobs: 2, KeepGoing: true}
+
+	var out strings.Builder
+	err := runExplain(cfg, "", true, opts, true, &out)
+	if err == nil || !strings.Contains(err.Error(), "billing") {
+		t.Errorf("expected the strict scope to fail explain, got %v", err)
+	}
+
+	var explained struct {
+		Results []ExplainResult `json:"results"`
+	}
+	if err := json.Unmarshal([]byte(out.String()), &explained); err != nil {
+		t.Fatalf("invalid output %q: %v", out.String(), err)
+	}
+	var targets []string
+	for _, e := range explained.Results {
+		targets = append(targets, e.Target)
+		if e.Product != "app" {
+			if e.Error != "" {
+				t.Errorf("%s: unexpected error %q", e.Target, e.Error)
+			}
+			continue
+		}
+		if !strings.Contains(e.Error, `unknown scope "billing"`) {
+			t.Errorf("%s: expected the scope error, got %q", e.Target, e.Error)
+		}
+		var rejected bool
+		for _, c := range e.Commits {
+			rejected = rejected || c.ScopeHandling == matcher.ScopeRejected
+		}
+		if !rejected {
+			t.Errorf("%s: expected the rejected commit to be explained, got %+v", e.Target, e.Commits)
+		}
+	}
+	if got, want := strings.Join(targets, " "), "api app-free app-pro docs lib web"; got != want {
+		t.Errorf("expected every target, got %s", got)
+	}
+
+	out.Reset()
+	runExplain(cfg, "app-pro", false, opts, false, &out)
+	if !strings.Contains(out.String(), "Error:    failed to calculate for app-pro") || !strings.Contains(out.String(), "rejected") {
+		t.Errorf("expected the table to show the error and the rejected commit, got:\n%s", out.String())
+	}
+}
+
+// unreachableTagRepo creates a repository where app-v2.0.0 is on a branch that was
+// never merged, and main has a feature since app-v1.0.0.
+func unreachableTagRepo(t *testing.T) {
+	t.Helper()
+	dir := testRepo(t)
+	commitFiles(t, dir, "chore: initial", "app/a")
+	runGit(t, dir, "tag", "app-v1.0.0")
+	runGit(t, dir, "checkout", "-q", "-b", "next")
+	commitFiles(t, dir, "feat!: next major", "app/a")
+	runGit(t, dir, "tag", "app-v2.0.0")
+	runGit(t, dir, "checkout", "-q", "main")
+	commitFiles(t, dir, "feat: app feature", "app/a")
+}
+
+func TestCalculateResults_UnreachableTagWarning(t *testing.T) {
+	unreachableTagRepo(t)
+	cfg := parseConfig(t, "products:\n  app: {globs: [\"app/**\"]}\n")
+
+	results, err := calculateResults(cfg, "app", false, calcOptions{Jobs: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r := results[0]
+	if r.Next != "1.1.0" {
+		t.Errorf("expected 1.1.0 from the reachable tag, got %s", r.Next)
+	}
+	if len(r.Warnings) != 1 || !strings.HasPrefix(r.Warnings[0], "ignoring app-v2.0.0: not reachable from HEAD") {
+		t.Errorf("expected the unreachable tag warning, got %q", r.Warnings)
+	}
+}
+
+func TestResolveBranchRule_BitriseFallbackOnlyAtHead(t *testing.T) {
+	dir := testRepo(t)
+	commitFiles(t, dir, "chore: initial", "app/a")
+	runGit(t, dir, "tag", "app-v1.0.0")
+	runGit(t, dir, "checkout", "-q", "--detach")
+	t.Setenv("BITRISE_GIT_BRANCH", "develop")
+	t.Cleanup(func() {
+		git.SetRef("")
+		git.SetVersionLine(nil)
+	})
+	cfg := parseConfig(t, "branches:\n  develop: {prerelease: beta}\nproducts:\n  app: {globs: [\"app/**\"]}\n")
+
+	var opts calcOptions
+	if err := resolveBranchRule(cfg, &opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if opts.branch == nil || opts.branch.Name != "develop" || opts.Prerelease != "beta" {
+		t.Errorf("expected the develop rule from BITRISE_GIT_BRANCH at a detached HEAD, got %+v, %q", opts.branch, opts.Prerelease)
+	}
+
+	// A tag says nothing about the branch Bitrise checked out
+	git.SetRef("app-v1.0.0")
+	opts = calcOptions{}
+	if err := resolveBranchRule(cfg, &opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if opts.branch != nil || opts.Prerelease != "" {
+		t.Errorf("expected no branch rule for a tag ref, got %+v, %q", opts.branch, opts.Prerelease)
+	}
+}
+
+func TestRunExplain_ReportsFailedFetches(t *testing.T) {
+	dir := testRepo(t)
+	commitFiles(t, dir, "chore: initial", "app/a")
+	runGit(t, dir, "tag", "app-v1.0.0")
+	// A single commit since the tag looks like missing history, but there is no remote
+	commitFiles(t, dir, "fix: app fix", "app/a")
+	cfg := parseConfig(t, "products:\n  app: {globs: [\"app/**\"]}\n")
+
+	before := len(git.Fetches())
+	var out strings.Builder
+	var runErr error
+	stderr := captureStderr(t, func() {
+		runErr = runExplain(cfg, "app", false, calcOptions{Jobs: 1}, true, &out)
+	})
+	if runErr != nil {
+		t.Fatalf("unexpected error: %v", runErr)
+	}
+	if strings.Contains(stderr, "[INFO] Fetched additional git history") || !strings.Contains(stderr, "[WARN] Could not fetch additional git history: git fetch origin") {
+		t.Errorf("expected the failed fetch to be reported, got:\n%s", stderr)
+	}
+
+	var result ExplainResult
+	if err := json.Unmarshal([]byte(out.String()), &result); err != nil {
+		t.Fatalf("invalid JSON %q: %v", out.String(), err)
+	}
+	// Fetches are recorded for the whole process, so earlier tests' fetches come first
+	if len(result.Fetches) <= before {
+		t.Fatalf("expected this run's fetches in the explain output, got %+v", result.Fetches)
+	}
+	for _, f := range result.Fetches[before:] {
+		if !strings.HasPrefix(f.Command, "git fetch origin") || f.Error == "" {
+			t.Errorf("expected a failed fetch from origin, got %+v", f)
+		}
+	}
+}
+
+// restoreSetupGlobals restores the package settings that commonFlags.setup changes
+// when the test ends.
+func restoreSetupGlobals(t *testing.T) {
+	t.Helper()
+	oldVerbose := verbose
+	oldBackend, oldRef := git.CurrentBackend(), git.Ref()
+	oldFirstParent, oldTagSelection := git.FirstParent(), git.TagSelection()
+	oldFetchPolicy, oldRemote := git.FetchPolicy(), git.Remote()
+	t.Cleanup(func() {
+		verbose = oldVerbose
+		git.SetBackend(oldBackend)
+		git.SetRef(oldRef)
+		git.SetFirstParent(oldFirstParent)
+		git.SetTagSelection(oldTagSelection)
+		git.SetFetchPolicy(oldFetchPolicy)
+		git.SetRemote(oldRemote)
+	})
+}
+
+func TestSetup_TagSelection(t *testing.T) {
+	unreachableTagRepo(t)
+	restoreSetupGlobals(t)
+	content := "products:\n  app: {globs: [\"app/**\"]}\n"
+
+	tests := []struct {
+		name    string
+		args    []string
+		env     string
+		wantTag string
+	}{
+		{"default", nil, "", "app-v1.0.0"},
+		{"config", []string{"--config-content", "tag_selection: highest\n" + content}, "", "app-v2.0.0"},
+		{"flag", []string{"--tag-selection", "highest"}, "", "app-v2.0.0"},
+		{"flag overrides config", []string{"--config-content", "tag_selection: highest\n" + content, "--tag-selection", "reachable"}, "", "app-v1.0.0"},
+		{"environment", nil, "highest", "app-v2.0.0"},
+		{"environment overrides flag", []string{"--tag-selection", "highest"}, "reachable", "app-v1.0.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("tag_selection", tt.env)
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			f := registerCommonFlags(fs)
+			if err := fs.Parse(append([]string{"--config-content", content}, tt.args...)); err != nil {
+				t.Fatal(err)
+			}
+			f.setup()
+
+			tag, _, err := git.FindLastTagByPrefix("app")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tag != tt.wantTag {
+				t.Errorf("expected %s, got %s", tt.wantTag, tag)
+			}
+		})
+	}
+}
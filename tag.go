@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/jimdowning-cyclops/semver-calc-go/internal/config"
+	"github.com/jimdowning-cyclops/semver-calc-go/internal/git"
+	"github.com/jimdowning-cyclops/semver-calc-go/internal/version"
+)
+
+// defaultTagMessage is the default template for annotated tag messages.
+const defaultTagMessage = "Release {{.Product}}{{with .Variant}} {{.}}{{end}} {{.Next}}"
+
+// TagResult is the JSON output for a single tag created by the tag subcommand.
+type TagResult struct {
+	Tag     string `json:"tag"`
+	Product string `json:"product"`
+	Variant string `json:"variant,omitempty"`
+	Version string `json:"version"`
+	Pushed  bool   `json:"pushed"`
+}
+
+// TagOutput is the JSON output of the tag subcommand.
+type TagOutput struct {
+	Tags    []TagResult   `json:"tags"`
+	Fetches []FetchResult `json:"fetches,omitempty"` // Fetches run to complete the git history
+}
+
+// tagOptions controls how the tag subcommand creates and pushes tags.
+type tagOptions struct {
+	Message *template.Template
+	Sign    string
+	SignKey string
+	Push    bool
+	Remote  string
+}
+
+// runTagCommand implements "semver-calc tag", which creates the computed tags.
+func runTagCommand(args []string) {
+	fs := flag.NewFlagSet("semver-calc tag", flag.ExitOnError)
+	f := registerCommonFlags(fs)
+	messageFlag := fs.String("message", defaultTagMessage, "Tag message template (Go text/template over the JSON result fields)")
+	signFlag := fs.String("sign", "", "Sign tags using gpg or ssh")
+	signKeyFlag := fs.String("sign-key", "", "Signing key (defaults to git's user.signingkey)")
+	pushFlag := fs.Bool("push", false, "Push created tags to the remote (see --remote)")
+	fs.Parse(args)
+
+	cfg, opts := f.setup()
+
+	switch *signFlag {
+	case "", "gpg", "ssh":
+	default:
+		fmt.Fprintf(os.Stderr, "error: --sign must be gpg or ssh, got %q\n", *signFlag)
+		os.Exit(1)
+	}
+
+	tmpl, err := template.New("message").Option("missingkey=error").Parse(*messageFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: invalid --message template: %v\n", err)
+		os.Exit(1)
+	}
+
+	tagOpts := tagOptions{
+		Message: tmpl,
+		Sign:    *signFlag,
+		SignKey: *signKeyFlag,
+		Push:    *pushFlag,
+		Remote:  f.remote,
+	}
+	if err := runTag(cfg, f.target, f.all, opts, tagOpts); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// plannedTag is a tag that runTag will create.
+type plannedTag struct {
+	name    string
+	message string
+	result  VariantResult
+}
+
+// runTag calculates versions exactly as runConfigMode does, then creates an annotated
+// tag "{TagName}-v{Next}" for every result with a bump. All tags are checked before any
+// is created, so nothing is tagged if one would duplicate or go below an existing tag.
+// If creating or pushing a tag fails, the tags created by this run are deleted again.
+func runTag(cfg *config.Config, target string, all bool, opts calcOptions, tagOpts tagOptions) error {
+	results, err := calculateResults(cfg, target, all, opts)
+	if err != nil {
+		return err
+	}
+
+	var planned []plannedTag
+	var problems []string
+	for _, r := range results {
+		if r.Error != "" {
+			debug("Skipping %s: %s", r.TagName, r.Error)
+			continue
+		}
+		if r.Bump == "none" {
+			debug("Skipping %s: no bump", r.TagName)
+			continue
+		}
+
+		next, err := version.Parse(r.Next)
+		if err != nil {
+			return fmt.Errorf("invalid next version %q for %s: %w", r.Next, r.TagName, err)
+		}
+		if err := git.CheckNewTag(r.TagName, next); err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+
+		var msg strings.Builder
+		if err := tagOpts.Message.Execute(&msg, r); err != nil {
+			return fmt.Errorf("failed to render tag message: %w", err)
+		}
+		planned = append(planned, plannedTag{name: git.FormatTagName(r.TagName, next), message: msg.String(), result: r})
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("refusing to create tags: %s", strings.Join(problems, "; "))
+	}
+
+	output := TagOutput{Tags: []TagResult{}}
+	var names []string
+	for _, p := range planned {
+		debug("Creating tag %s", p.name)
+		err := git.CreateTag(p.name, git.TagOptions{Message: p.message, Sign: tagOpts.Sign, SignKey: tagOpts.SignKey})
+		if err != nil {
+			return rollbackTags(names, err)
+		}
+		names = append(names, p.name)
+		output.Tags = append(output.Tags, TagResult{
+			Tag:     p.name,
+			Product: p.result.Product,
+			Variant: p.result.Variant,
+			Version: p.result.Next,
+		})
+	}
+
+	if tagOpts.Push && len(names) > 0 {
+		debug("Pushing %d tag(s) to %s", len(names), tagOpts.Remote)
+		if err := git.PushTags(tagOpts.Remote, names); err != nil {
+			return rollbackTags(names, err)
+		}
+		for i := range output.Tags {
+			output.Tags[i].Pushed = true
+		}
+	}
+
+	output.Fetches = fetchResults()
+	if err := json.NewEncoder(os.Stdout).Encode(output); err != nil {
+		return err
+	}
+	return failedResults(results)
+}
+
+// rollbackTags deletes the tags created by a failed run and returns its error,
+// mentioning any tags that could not be deleted.
+func rollbackTags(created []string, cause error) error {
+	if len(created) == 0 {
+		return cause
+	}
+	debug("Deleting tags created by this run: %s", strings.Join(created, ", "))
+	if err := git.DeleteTags(created); err != nil {
+		return fmt.Errorf("%w (and the tags created so far could not be deleted: %v)", cause, err)
+	}
+	return fmt.Errorf("%w (deleted the tags created so far: %s)", cause, strings.Join(created, ", "))
+}